@@ -0,0 +1,41 @@
+-- name: ListAllForms :many
+select * from list_all_forms(
+    sqlc.arg(user_id), sqlc.arg(search), sqlc.arg(limit_val), sqlc.arg(offset_val)
+);
+
+-- name: CountAllForms :one
+select count_all_forms(sqlc.arg(user_id), sqlc.arg(search));
+
+-- name: ListAllUsers :many
+select * from list_all_users(
+    sqlc.arg(user_id), sqlc.arg(search), sqlc.arg(limit_val), sqlc.arg(offset_val)
+);
+
+-- name: CountAllUsers :one
+select count_all_users(sqlc.arg(user_id), sqlc.arg(search));
+
+-- name: ListAllGroups :many
+select * from list_all_groups(
+    sqlc.arg(user_id), sqlc.arg(search), sqlc.arg(limit_val), sqlc.arg(offset_val)
+);
+
+-- name: CountAllGroups :one
+select count_all_groups(sqlc.arg(user_id), sqlc.arg(search));
+
+-- name: ReassignForm :one
+select * from reassign_form(
+    sqlc.arg(form_id), sqlc.arg(user_id), sqlc.arg(target_user)
+);
+
+-- name: ReassignGroup :one
+select * from reassign_group(
+    sqlc.arg(group_id), sqlc.arg(user_id), sqlc.arg(target_user)
+);
+
+-- name: RemoveForm :exec
+select remove_form(sqlc.arg(form_id), sqlc.arg(user_id));
+
+-- name: SetUserDisabled :one
+select * from set_user_disabled(
+    sqlc.arg(target_user_id), sqlc.arg(user_id), sqlc.arg(disabled)
+);
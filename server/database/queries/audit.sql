@@ -0,0 +1,13 @@
+-- name: ListFormAudit :many
+select * from list_audit_for_form(
+    sqlc.arg(form_id), sqlc.arg(user_id), sqlc.arg(limit_val), sqlc.arg(offset_val)
+);
+
+-- name: CountFormAudit :one
+select count_audit_for_form(sqlc.arg(form_id), sqlc.arg(user_id));
+
+-- name: ListAuditLog :many
+select * from list_audit_log(
+    sqlc.arg(user_id), sqlc.narg(actor), sqlc.narg(action), sqlc.narg(form), sqlc.narg(group_id),
+    sqlc.arg(limit_val), sqlc.arg(offset_val)
+);
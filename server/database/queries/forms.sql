@@ -31,17 +31,20 @@ select * from create_form_with_permissions(
     sqlc.arg(anonymous),
     sqlc.narg(max_responses),
     sqlc.arg(individual_limit),
-    sqlc.arg(editable_responses)
+    sqlc.arg(editable_responses),
+    sqlc.narg(edit_deadline),
+    sqlc.narg(edit_window),
+    sqlc.arg(quiz)
 );
 
 -- name: ResolveFormByHandleAndSlug :one
 select * from resolve_form_by_handle_and_slug(
-    sqlc.arg(handle), sqlc.arg(slug), sqlc.arg(user_id)
+    sqlc.arg(handle), sqlc.arg(slug), sqlc.narg(user_id)
 );
 
 -- name: GetFormByID :one
 select * from get_form_by_id(
-    sqlc.arg(id), sqlc.arg(user_id)
+    sqlc.arg(id), sqlc.arg(user_id), sqlc.narg(version)
 );
 
 -- name: UpdateFormByID :one
@@ -58,7 +61,17 @@ select * from update_form_by_id(
     sqlc.narg(anonymous),
     sqlc.narg(max_responses),
     sqlc.narg(individual_limit),
-    sqlc.narg(editable_responses)
+    sqlc.narg(editable_responses),
+    sqlc.narg(edit_deadline),
+    sqlc.narg(edit_window),
+    sqlc.narg(quiz)
+);
+
+-- name: ReleaseScores :one
+select * from release_scores_for_form(
+    sqlc.arg(id),
+    sqlc.arg(user_id),
+    sqlc.arg(released)
 );
 
 -- name: DeleteFormByID :exec
@@ -66,3 +79,43 @@ select delete_form_by_id(
     sqlc.arg(id),
     sqlc.arg(user_id)
 );
+
+-- name: ListFormVersions :many
+select * from list_versions_for_form(
+    sqlc.arg(form_id),
+    sqlc.arg(user_id),
+    sqlc.arg(limit_val),
+    sqlc.arg(offset_val)
+);
+
+-- name: CountFormVersions :one
+select count_versions_for_form(
+    sqlc.arg(form_id),
+    sqlc.arg(user_id)
+);
+
+-- name: GetFormVersion :one
+select * from get_version_for_form(
+    sqlc.arg(form_id),
+    sqlc.arg(user_id),
+    sqlc.arg(version)
+);
+
+-- name: FindMovedForm :one
+select * from find_moved_form(
+    sqlc.arg(handle), sqlc.arg(slug), sqlc.narg(user_id)
+);
+
+-- name: OfferFormTransfer :one
+select * from offer_form_transfer(
+    sqlc.arg(form_id), sqlc.arg(user_id), sqlc.arg(target_user)
+);
+
+-- name: ListTransfers :many
+select * from list_transfers_for_user(sqlc.arg(user_id));
+
+-- name: CancelFormTransfer :exec
+select cancel_form_transfer(sqlc.arg(form_id), sqlc.arg(user_id));
+
+-- name: AcceptFormTransfer :one
+select * from accept_form_transfer(sqlc.arg(form_id), sqlc.arg(user_id));
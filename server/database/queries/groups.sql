@@ -46,7 +46,51 @@ select add_group_member_by_email(
     sqlc.arg(group_id), sqlc.arg(user_id), sqlc.arg(target_user)
 );
 
+-- name: InviteToGroup :one
+select * from invite_to_group(
+    sqlc.arg(group_id), sqlc.arg(user_id), sqlc.arg(email)
+);
+
 -- name: RemoveGroupMember :exec
 select remove_group_member_by_id(
     sqlc.arg(group_id), sqlc.arg(user_id), sqlc.arg(target_user_id)
 );
+
+-- name: ListGroupInvites :many
+select * from list_invites_for_group(sqlc.arg(group_id), sqlc.arg(user_id));
+
+-- name: CancelGroupInvite :exec
+select cancel_invite_to_group(
+    sqlc.arg(group_id), sqlc.arg(user_id), sqlc.arg(invite_id)
+);
+
+-- name: ListSubgroups :many
+select * from list_subgroups_for_group(sqlc.arg(group_id), sqlc.arg(user_id));
+
+-- name: AddSubgroup :one
+select * from add_subgroup_to_group(
+    sqlc.arg(group_id), sqlc.arg(user_id), sqlc.arg(subgroup_id), sqlc.arg(exclude)
+);
+
+-- name: RemoveSubgroup :exec
+select remove_subgroup_from_group(
+    sqlc.arg(group_id), sqlc.arg(user_id), sqlc.arg(subgroup_id)
+);
+
+-- name: ListGroupManagers :many
+select * from list_managers_for_group(sqlc.arg(group_id), sqlc.arg(user_id));
+
+-- name: AddGroupManager :one
+select * from add_group_manager_by_email(
+    sqlc.arg(group_id), sqlc.arg(user_id), sqlc.arg(target_user)
+);
+
+-- name: RemoveGroupManager :exec
+select remove_group_manager_by_id(
+    sqlc.arg(group_id), sqlc.arg(user_id), sqlc.arg(target_user_id)
+);
+
+-- name: TransferGroup :one
+select * from transfer_group_by_id(
+    sqlc.arg(id), sqlc.arg(user_id), sqlc.arg(target_user)
+);
@@ -4,11 +4,38 @@ select * from list_permissions_for_form(sqlc.arg(form_id), sqlc.arg(user_id));
 -- name: GrantPermission :one
 select * from grant_permission_on_form(
     sqlc.arg(form_id), sqlc.arg(user_id),
-    sqlc.narg(target_user), sqlc.narg(target_group),
-    sqlc.arg(role)::permission_role
+    sqlc.narg(target_user), sqlc.narg(target_group), sqlc.arg(anyone),
+    sqlc.arg(role)::permission_role, sqlc.narg(starts), sqlc.narg(expires)
+);
+
+-- name: InviteToForm :one
+select * from invite_to_form(
+    sqlc.arg(form_id), sqlc.arg(user_id), sqlc.arg(email),
+    sqlc.arg(role)::permission_role, sqlc.narg(starts), sqlc.narg(expires)
+);
+
+-- name: ListFormInvites :many
+select * from list_invites_for_form(sqlc.arg(form_id), sqlc.arg(user_id));
+
+-- name: CancelFormInvite :exec
+select cancel_invite_to_form(
+    sqlc.arg(form_id), sqlc.arg(user_id), sqlc.arg(invite_id)
 );
 
 -- name: RevokePermission :exec
 select revoke_permission_by_id(
     sqlc.arg(form_id), sqlc.arg(user_id), sqlc.arg(permission_id)
 );
+
+-- name: HasFormPermission :one
+select has_form_permission(
+    sqlc.arg(user_id), sqlc.arg(form_id), sqlc.arg(role)::permission_role
+);
+
+-- name: ListPermissionSources :many
+select * from list_permission_sources(sqlc.narg(user_id), sqlc.arg(form_id));
+
+-- name: GetFormAccess :one
+select * from get_access_for_form(
+    sqlc.arg(form_id), sqlc.narg(user_id), sqlc.arg(token)
+);
@@ -16,38 +16,118 @@ select count_responses_for_form(
     sqlc.arg(status)::response_status
 );
 
+-- name: GetFormForAnalysis :one
+select * from get_form_for_analysis(
+    sqlc.arg(form_id),
+    sqlc.arg(user_id)
+);
+
+-- name: ListVersionsForAnalysis :many
+select * from list_versions_for_analysis(
+    sqlc.arg(form_id),
+    sqlc.arg(user_id)
+);
+
+-- name: ExportResponses :many
+select * from export_responses_for_form(
+    sqlc.arg(form_id),
+    sqlc.arg(user_id),
+    sqlc.arg(status)::response_status,
+    sqlc.narg(after),
+    sqlc.arg(limit_val)
+);
+
+-- name: CountChoices :many
+select * from count_choices_for_form(
+    sqlc.arg(form_id),
+    sqlc.arg(user_id),
+    sqlc.arg(status)::response_status,
+    sqlc.arg(questions)::text[]
+);
+
+-- name: SummarizeNumbers :many
+select * from summarize_numbers_for_form(
+    sqlc.arg(form_id),
+    sqlc.arg(user_id),
+    sqlc.arg(status)::response_status,
+    sqlc.arg(questions)::text[]
+);
+
+-- name: SummarizeText :many
+select * from summarize_text_for_form(
+    sqlc.arg(form_id),
+    sqlc.arg(user_id),
+    sqlc.arg(status)::response_status,
+    sqlc.arg(questions)::text[],
+    sqlc.arg(samples)
+);
+
 -- name: StartResponse :one
 select * from start_response_for_form(
     sqlc.arg(form_id),
-    sqlc.arg(user_id)
+    sqlc.narg(user_id),
+    sqlc.arg(token),
+    sqlc.narg(key)
 );
 
 -- name: GetResponse :one
 select * from get_response_by_id(
     sqlc.arg(id),
     sqlc.arg(form_id),
-    sqlc.arg(user_id)
+    sqlc.narg(user_id),
+    sqlc.narg(key)
 );
 
 -- name: GetAnswers :many
 select * from get_answers_for_response(
     sqlc.arg(id),
     sqlc.arg(form_id),
-    sqlc.arg(user_id)
+    sqlc.narg(user_id),
+    sqlc.narg(key)
+);
+
+-- name: GetFormForResponse :one
+select * from get_form_for_response(
+    sqlc.arg(id),
+    sqlc.arg(form_id),
+    sqlc.narg(user_id),
+    sqlc.narg(key)
 );
 
 -- name: SaveAnswer :one
 select * from add_answer_to_response(
-    sqlc.arg(id), sqlc.arg(form_id), sqlc.arg(user_id),
+    sqlc.arg(id), sqlc.arg(form_id), sqlc.narg(user_id), sqlc.narg(key),
     sqlc.arg(question), sqlc.arg(value)
 );
 
+-- name: AdvanceRevision :one
+select advance_response_revision(
+    sqlc.arg(id), sqlc.arg(form_id), sqlc.narg(user_id), sqlc.narg(key),
+    sqlc.narg(revision)::int
+);
+
+-- name: RemoveAnswers :exec
+select remove_answers_from_response(
+    sqlc.arg(id), sqlc.arg(form_id), sqlc.narg(user_id), sqlc.narg(key),
+    sqlc.arg(questions)::text[]
+);
+
+-- name: ListAnswerHistory :many
+select * from list_history_for_response(
+    sqlc.arg(id),
+    sqlc.arg(form_id),
+    sqlc.arg(user_id)
+);
+
 -- name: SubmitResponse :one
 select * from submit_response_by_id(
     sqlc.arg(id),
     sqlc.arg(form_id),
-    sqlc.arg(user_id),
-    sqlc.arg(save)
+    sqlc.narg(user_id),
+    sqlc.narg(key),
+    sqlc.arg(token),
+    sqlc.arg(save),
+    sqlc.narg(score)
 );
 
 -- name: ListSavedResponses :many
@@ -0,0 +1,18 @@
+-- name: CreateSession :one
+select * from create_session(
+    sqlc.arg(user_id), sqlc.arg(expires), sqlc.narg(ip), sqlc.narg(user_agent)
+);
+
+-- name: TouchSession :one
+select * from touch_session(
+    sqlc.arg(id), sqlc.arg(user_id), sqlc.narg(ip), sqlc.narg(user_agent)
+);
+
+-- name: ListSessions :many
+select * from list_sessions_for_user(sqlc.arg(user_id));
+
+-- name: RevokeSession :exec
+select revoke_session(sqlc.arg(id), sqlc.arg(user_id));
+
+-- name: RevokeAllSessions :exec
+select revoke_sessions_for_user(sqlc.arg(user_id));
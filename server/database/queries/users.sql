@@ -1,8 +1,10 @@
 -- name: EnsureUser :one
-with x as (
-    insert into users (handle, email, name) values ($1, $2, $3)
-    on conflict do nothing returning *
-) select * from x union select * from users where email = $2;
+select * from ensure_user(
+    sqlc.arg(handle), sqlc.arg(email), sqlc.arg(name), sqlc.arg(admin)
+);
 
 -- name: GetUserById :one
-select * from users where id = $1;
\ No newline at end of file
+select * from users where id = $1;
+
+-- name: SyncAdmins :exec
+update users set admin = handle = any(sqlc.arg(handles)::text[]);
@@ -1,4 +1,6 @@
+-- roles are ordered, each implying the ones before it
 begin; create type permission_role as enum ('respond', 'view', 'comment', 'analyze', 'edit', 'manage'); commit;
 begin; create type group_type as enum ('list', 'domain'); commit;
+begin; create type group_role as enum ('member', 'manager', 'owner'); commit;
 begin; create type response_status as enum ('draft', 'completed', 'edited'); commit;
 begin; create type comment_state as enum ('visible', 'hidden'); commit;
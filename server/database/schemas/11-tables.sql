@@ -2,16 +2,32 @@ create table if not exists users (
     id text primary key default generate_ulid(),
     handle text not null unique, -- cas user id
     email text not null unique,
-    name text not null
+    name text not null,
+    admin boolean not null default false, -- kept in sync with the configured handles
+    disabled boolean not null default false
+);
+
+-- logins, so they can be listed and revoked. the cookie only holds the id
+create table if not exists sessions (
+    id text primary key default generate_ulid(),
+    "user" text not null references users(id) on delete cascade,
+    created timestamptz not null default now(),
+    last_seen timestamptz not null default now(),
+    expires timestamptz not null,
+    ip text,
+    user_agent text
 );
 
+create index if not exists sessions_user on sessions ("user");
+
 create table if not exists forms (
     id text primary key default generate_ulid(),
     owner text not null references users(id) on delete cascade,
     slug text not null,
     title text not null,
     description text,
-    structure text not null,
+    structure text not null, -- copy of the structure of the latest version
+    version int not null default 1,
     modified timestamptz not null default now(),
     live boolean not null default false,
     opens timestamptz,
@@ -20,11 +36,46 @@ create table if not exists forms (
     max_responses int,
     individual_limit int not null default 1,
     editable_responses boolean not null default false,
+    edit_deadline timestamptz, -- responses cannot be edited after this time
+    edit_window int, -- seconds after submission during which a response can be edited
+    quiz boolean not null default false,
+    scores_released boolean not null default false,
 
     unique (owner, slug),
     constraint response_limits_check check (
         individual_limit >= 1 and max_responses >= individual_limit
-    )
+    ),
+    constraint edit_window_check check (edit_window > 0)
+);
+
+-- forms offered to another user, who becomes the owner once they accept
+create table if not exists form_transfers (
+    form text primary key references forms(id) on delete cascade,
+    recipient text not null references users(id) on delete cascade,
+    sender text references users(id) on delete set null,
+    created timestamptz not null default now()
+);
+
+-- earlier urls of forms that changed owners, which still lead to them
+create table if not exists form_redirects (
+    handle text not null,
+    slug text not null,
+    form text not null references forms(id) on delete cascade,
+
+    primary key (handle, slug)
+);
+
+-- note: this table is empty, only exists for sqlc to understand the type
+create table if not exists form_locations (handle text not null, slug text not null);
+
+create table if not exists form_versions (
+    form text not null references forms(id) on delete cascade,
+    version int not null,
+    structure text not null,
+    author text references users(id) on delete set null,
+    created timestamptz not null default now(),
+
+    primary key (form, version)
 );
 
 create table if not exists comments (
@@ -66,41 +117,121 @@ create table if not exists group_list_members (
     primary key ("group", "user")
 );
 
+-- users other than the owner who can change a group
+create table if not exists group_managers (
+    "group" text not null references groups(id) on delete cascade,
+    "user" text not null references users(id) on delete cascade,
+
+    primary key ("group", "user")
+);
+
+-- groups nested in list groups, whose members are members of the group too,
+-- or are left out of it if the group is excluded
+create table if not exists group_subgroups (
+    "group" text not null references groups(id) on delete cascade,
+    subgroup text not null references groups(id) on delete cascade,
+    exclude boolean not null default false,
+
+    primary key ("group", subgroup),
+    constraint subgroup_not_group check ("group" <> subgroup)
+);
+
+create index if not exists group_subgroups_subgroup on group_subgroups (subgroup);
+
+-- members of list groups who have not logged in yet, who join the group when
+-- they first log in
+create table if not exists pending_group_members (
+    id text primary key default generate_ulid(),
+    "group" text not null references groups(id) on delete cascade,
+    email text not null,
+    inviter text references users(id) on delete set null,
+    created timestamptz not null default now(),
+
+    unique ("group", email)
+);
+
 create table if not exists form_permissions (
     id text primary key default generate_ulid(),
     form text not null references forms(id) on delete cascade,
     role permission_role not null,
     "user" text references users(id) on delete cascade,
     "group" text references groups(id) on delete cascade,
+    anyone boolean not null default false, -- anyone with the link, including guests
+    starts timestamptz, -- the role is only in effect from this time
+    expires timestamptz, -- and until this time
 
     constraint permit_user_or_group check (
-        ("user" is not null and "group" is null) or
-        ("user" is null and "group" is not null)
-    )
+        ("user" is not null and "group" is null and not anyone) or
+        ("user" is null and "group" is not null and not anyone) or
+        ("user" is null and "group" is null and anyone)
+    ),
+    constraint permit_anyone_to_view_or_respond check (
+        not anyone or role in ('view', 'respond')
+    ),
+    constraint permission_validity_check check (expires > starts)
 );
 
+-- a single row per grantee, holding the highest role they were given
 create unique index form_permissions_user_unique
-on form_permissions (form, "user", role) where "group" is null;
+on form_permissions (form, "user") where "user" is not null;
 
 create unique index form_permissions_group_unique
-on form_permissions (form, "group", role) where "user" is null;
+on form_permissions (form, "group") where "group" is not null;
+
+create unique index form_permissions_anyone_unique
+on form_permissions (form) where anyone;
+
+-- grants for emails that no user has logged in with yet, which are given to
+-- the user when they first log in
+create table if not exists pending_permissions (
+    id text primary key default generate_ulid(),
+    form text not null references forms(id) on delete cascade,
+    email text not null,
+    role permission_role not null,
+    starts timestamptz,
+    expires timestamptz,
+    inviter text references users(id) on delete set null,
+    created timestamptz not null default now(),
+
+    unique (form, email)
+);
+
+-- note: these tables are empty, only exist for sqlc to understand the types
+create table if not exists permission_sources (
+    permission text not null, role permission_role not null,
+    source text not null, -- anyone, user, list or domain
+    "group" text
+);
+
+create table if not exists form_access (
+    open boolean not null, reason text,
+    individual_limit int not null, remaining int not null
+);
 
 create table if not exists submission_records (
     form text not null references forms(id) on delete cascade,
-    "user" text not null references users(id) on delete cascade,
+    -- the id of the user, or for anonymous forms, a token derived from it that
+    -- cannot be traced back to them
+    participant text not null,
     responses int not null default 1,
 
-    primary key ("form", "user")
+    primary key ("form", participant)
 );
 
 create table if not exists responses (
     id text primary key default generate_ulid(),
     form text not null references forms(id) on delete cascade,
-    respondent text references users(id),
+    respondent text references users(id), -- never set for anonymous forms
+    key_hash text, -- hash of the key respondents to anonymous forms are given
+    version int not null, -- version of the structure the response was started on
     status response_status not null default 'draft',
     started timestamptz not null default now(),
     submitted timestamptz,
-    edited timestamptz
+    edited timestamptz,
+    score int, -- only set for quizzes, once submitted
+    revision int not null default 0, -- incremented on every save of answers
+
+    foreign key (form, version) references form_versions(form, version)
 );
 
 create table if not exists answers (
@@ -114,6 +245,63 @@ create table if not exists answers (
     unique (response, question)
 );
 
+-- changes to the answers of responses after they were first submitted
+create table if not exists answer_history (
+    id text primary key default generate_ulid(),
+    response text not null references responses(id) on delete cascade,
+    question text not null,
+    old_value jsonb, -- null if the question was not answered before
+    new_value jsonb, -- null if the answer was removed
+    author text references users(id) on delete set null, -- null for anonymous forms and guests
+    changed timestamptz not null default now()
+);
+
+create index if not exists answer_history_response on answer_history (response);
+
+-- changes made to forms, groups, comments and responses, and who made them.
+-- entries are never changed or removed, and refer to what they describe
+-- without references, so that they outlive it
+create table if not exists audit_log (
+    id text primary key default generate_ulid(),
+    actor text, -- null for guests and respondents to anonymous forms
+    action text not null, -- e.g. permission.grant
+    form text,
+    "group" text,
+    target text, -- id of what was changed
+    before jsonb,
+    after jsonb,
+    created timestamptz not null default now()
+);
+
+create index if not exists audit_log_form on audit_log (form, created);
+create index if not exists audit_log_group on audit_log ("group", created);
+
+-- note: this table is empty, only exists for sqlc to understand the type
+create table if not exists response_exports (
+    id text not null, version int not null, status response_status not null,
+    started timestamptz not null, submitted timestamptz, edited timestamptz,
+    score int, respondent_handle text, respondent_email text,
+    answers jsonb not null, edited_questions text[]
+);
+
+-- note: these tables are empty, only exist for sqlc to understand the types
+create table if not exists choice_counts (
+    question text not null, category text, value text not null,
+    count bigint not null, answered bigint not null
+);
+
+create table if not exists number_summaries (
+    question text not null, answered bigint not null,
+    mean double precision, median double precision
+);
+
+create table if not exists text_summaries (
+    question text not null, answered bigint not null,
+    mean_chars double precision, min_chars int, max_chars int,
+    mean_words double precision, min_words int, max_words int,
+    samples text[]
+);
+
 create table if not exists saved_responses (
     "user" text not null references users(id) on delete cascade,
     form text not null references forms(id) on delete cascade,
@@ -1,3 +1,143 @@
+-- the actor is null for guests, and for respondents to anonymous forms
+create or replace function record_audit(
+    p_actor text,
+    p_action text,
+    p_form text,
+    p_group text,
+    p_target text,
+    p_before jsonb,
+    p_after jsonb
+) returns void as $$
+begin
+    insert into audit_log (actor, action, form, "group", target, before, after)
+    values (p_actor, p_action, p_form, p_group, p_target, p_before, p_after);
+end;
+$$ language plpgsql;
+
+create or replace function reject_audit_log_changes()
+returns trigger as $$
+begin
+    raise exception 'The audit log cannot be changed.' using hint = 'forbidden';
+end;
+$$ language plpgsql;
+
+create or replace trigger audit_log_append_only
+before update or delete on audit_log
+for each row execute function reject_audit_log_changes();
+
+create or replace function is_admin(
+    p_user_id text
+) returns boolean as $$
+begin
+    return exists (
+        select 1 from users u where u.id = p_user_id and u.admin and not u.disabled
+    );
+end;
+$$ language plpgsql;
+
+create or replace function list_audit_for_form(
+    p_form_id text,
+    p_user_id text,
+    p_limit int,
+    p_offset int
+) returns setof audit_log as $$
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
+    end if;
+
+    return query select * from audit_log where form = p_form_id
+    order by created desc, id desc
+    limit p_limit offset p_offset;
+end;
+$$ language plpgsql;
+
+create or replace function count_audit_for_form(
+    p_form_id text,
+    p_user_id text
+) returns bigint as $$
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
+    end if;
+
+    return (select count(*) from audit_log where form = p_form_id);
+end;
+$$ language plpgsql;
+
+create or replace function list_audit_log(
+    p_user_id text,
+    p_actor text,
+    p_action text,
+    p_form text,
+    p_group text,
+    p_limit int,
+    p_offset int
+) returns setof audit_log as $$
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    return query select * from audit_log a
+    where (p_actor is null or a.actor = p_actor)
+      and (p_action is null or a.action like p_action || '%')
+      and (p_form is null or a.form = p_form)
+      and (p_group is null or a."group" = p_group)
+    order by a.created desc, a.id desc
+    limit p_limit offset p_offset;
+end;
+$$ language plpgsql;
+
+create or replace function permission_in_effect(
+    p_permission form_permissions
+) returns boolean as $$
+begin
+    return (p_permission.starts is null or p_permission.starts <= now()) and
+        (p_permission.expires is null or p_permission.expires > now());
+end;
+$$ language plpgsql;
+
+-- every grant in effect that applies to a user on a form, and where it comes
+-- from.
+-- p_user_id is null for guests, who only have the roles given to anyone
+create or replace function list_permission_sources(
+    p_user_id text,
+    p_form_id text
+) returns setof permission_sources as $$
+begin
+    return query
+    select fp.id, fp.role, 'anyone', null::text
+    from form_permissions as fp
+    where
+        fp.form = p_form_id and
+        fp.anyone and
+        permission_in_effect(fp)
+
+    union all
+
+    select fp.id, fp.role, 'user', null::text
+    from form_permissions as fp
+    where
+        fp.form = p_form_id and
+        fp."user" = p_user_id and
+        permission_in_effect(fp)
+
+    union all
+
+    select fp.id, fp.role, g.type::text, g.id
+    from form_permissions as fp
+    join groups as g on fp."group" = g.id
+    where
+        fp.form = p_form_id and
+        permission_in_effect(fp) and
+        g.id in (select list_member_groups_of_user(p_user_id))
+
+    order by 2 desc;
+end;
+$$ language plpgsql;
+
+-- roles are ordered, each one implying those before it in permission_role.
 create or replace function has_form_permission(
     p_user_id text,
     p_form_id text,
@@ -5,41 +145,48 @@ create or replace function has_form_permission(
 ) returns boolean as $$
 begin
     return exists (
-        select 1 from form_permissions
-        where
-            form = p_form_id and
-            role = p_required_role and
-            "user" = p_user_id
+        select 1 from list_permission_sources(p_user_id, p_form_id) as ps
+        where ps.role >= p_required_role
+    );
+end;
+$$ language plpgsql;
 
-        union all
+-- null if the user has no role in the group
+create or replace function get_group_role(
+    p_user_id text,
+    p_group_id text
+) returns group_role as $$
+begin
+    if exists (select 1 from groups where id = p_group_id and owner = p_user_id) then
+        return 'owner';
+    end if;
 
-        select 1 from form_permissions as fp
-        join groups as g on fp."group" = g.id
-        join group_list_members as glm on g.id = glm."group"
-        where
-            fp.form = p_form_id and
-            fp.role = p_required_role and
-            g.type = 'list' and
-            glm."user" = p_user_id
+    if exists (
+        select 1 from group_managers gm
+        where gm."group" = p_group_id and gm."user" = p_user_id
+    ) then
+        return 'manager';
+    end if;
 
-        union all
+    if is_group_member(p_group_id, p_user_id) then
+        return 'member';
+    end if;
 
-        select 1 from form_permissions as fp
-        join groups as g on fp."group" = g.id
-        join group_domain_rules as gdr on g.id = gdr."group"
-        where
-            fp.form = p_form_id and
-            fp.role = p_required_role and
-            g.type = 'domain' and
-            gdr.domain = (
-                select substring(email from '@(.*)$')
-                from users
-                where id = p_user_id
-            )
-    );
+    return null;
+end;
+$$ language plpgsql;
+
+create or replace function has_group_role(
+    p_user_id text,
+    p_group_id text,
+    p_required_role group_role
+) returns boolean as $$
+begin
+    return coalesce(get_group_role(p_user_id, p_group_id) >= p_required_role, false);
 end;
 $$ language plpgsql;
 
+-- whether a user can change a group, which its owner and managers can
 create or replace function has_group_permission(
     p_user_id text,
     p_group_id text,
@@ -50,10 +197,11 @@ declare
 begin
     select exists (
         select 1 from groups
-        where id = p_group_id and owner = p_user_id and (
+        where id = p_group_id and (
             p_required_type is null or type = p_required_type
         )
-    ) into has_permission;
+    ) and has_group_role(p_user_id, p_group_id, 'manager'::group_role)
+    into has_permission;
 
     return has_permission;
 end;
@@ -72,7 +220,8 @@ create or replace function list_forms_for_user(
 begin
     return query select f.* from forms f
     inner join form_permissions fp on f.id = fp.form and fp.user = p_user_id
-    where (p_filter_role is null or fp.role = p_filter_role) and (
+    and permission_in_effect(fp)
+    where (p_filter_role is null or fp.role >= p_filter_role) and (
         p_owner_email is null or f.owner = (select id from users where email = p_owner_email
     )) and (p_form_title = '' or f.title %> p_form_title) group by f.id order by
         case when p_sort_by = 'modified' and p_order = 'asc' then f.modified end asc,
@@ -94,7 +243,8 @@ declare
 begin
     select count(distinct f.id) into v_count from forms f
     left join form_permissions fp on f.id = fp.form and fp.user = p_user_id
-    where (p_filter_role is null or fp.role = p_filter_role) and (
+    and permission_in_effect(fp)
+    where (p_filter_role is null or fp.role >= p_filter_role) and (
         p_owner_email is null or f.owner = (select id from users where email = p_owner_email
     )) and (p_form_title = '' or f.title %> p_form_title);
 
@@ -102,28 +252,75 @@ begin
 end;
 $$ language plpgsql;
 
+-- slugs named after the routes under a form cannot be used, since those routes
+-- would be matched instead of /forms/:handle/:slug
+create or replace function is_reserved_slug(
+    p_slug text
+) returns boolean as $$
+begin
+    return p_slug = any(array[
+        'analytics', 'audit', 'comments', 'invites', 'me', 'permissions',
+        'responses', 'scores', 'transfer', 'versions'
+    ]);
+end;
+$$ language plpgsql;
+
+-- forms given a slug before it was reserved are renamed, as they could not be
+-- reached by their url anyway
+do $$
+declare
+    v_form forms;
+    v_slug text;
+    v_suffix int;
+begin
+    for v_form in select * from forms f where is_reserved_slug(f.slug) loop
+        v_suffix := 1;
+        v_slug := v_form.slug || '-' || v_suffix;
+        while exists (select 1 from forms f where f.owner = v_form.owner and f.slug = v_slug) loop
+            v_suffix := v_suffix + 1;
+            v_slug := v_form.slug || '-' || v_suffix;
+        end loop;
+
+        update forms set slug = v_slug where id = v_form.id;
+    end loop;
+end;
+$$;
+
 create or replace function create_form_with_permissions(
     p_owner_id text, p_slug text, p_title text, p_description text,
     p_structure text, p_live boolean, p_opens timestamptz, p_closes timestamptz,
     p_anonymous boolean, p_max_responses int, p_individual_limit int,
-    p_editable_responses boolean
+    p_editable_responses boolean, p_edit_deadline timestamptz, p_edit_window int,
+    p_quiz boolean
 ) returns forms as $$
 declare
     v_form forms;
-    v_perms permission_role[] := array['view', 'respond', 'comment', 'analyze', 'edit', 'manage'];
 begin
+    if is_reserved_slug(p_slug) then
+        raise exception 'Slug % is the name of a route and cannot be used.', p_slug using hint = 'reserved-slug';
+    end if;
+
     insert into forms (
         owner, slug, title, description, structure,
         live, opens, closes, anonymous, max_responses, individual_limit,
-        editable_responses
+        editable_responses, edit_deadline, edit_window, quiz
     ) values (
         p_owner_id, p_slug, p_title, p_description,
         p_structure, p_live, p_opens, p_closes, p_anonymous,
-        p_max_responses, p_individual_limit, p_editable_responses
+        p_max_responses, p_individual_limit, p_editable_responses,
+        p_edit_deadline, p_edit_window, p_quiz
     ) returning * into v_form;
 
+    insert into form_versions (form, version, structure, author)
+    values (v_form.id, v_form.version, v_form.structure, p_owner_id);
+
     insert into form_permissions (form, "user", role)
-    values (v_form.id, p_owner_id, unnest(v_perms));
+    values (v_form.id, p_owner_id, 'manage');
+
+    perform record_audit(
+        p_owner_id, 'form.create', v_form.id, null, v_form.id,
+        null, to_jsonb(v_form) - 'structure'
+    );
 
     return v_form;
 end;
@@ -153,9 +350,35 @@ begin
 end;
 $$ language plpgsql;
 
+-- where a form that changed owners can be found now, for its earlier url
+create or replace function find_moved_form(
+    p_handle text,
+    p_slug text,
+    p_user_id text
+) returns form_locations as $$
+declare
+    v_form_id text;
+    v_location form_locations;
+begin
+    select fr.form into v_form_id from form_redirects fr
+    where fr.handle = p_handle and fr.slug = p_slug;
+
+    if not found or not has_form_permission(p_user_id, v_form_id, 'respond'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    select u.handle, f.slug into v_location from forms f
+    join users u on f.owner = u.id
+    where f.id = v_form_id;
+
+    return v_location;
+end;
+$$ language plpgsql;
+
 create or replace function get_form_by_id(
     p_id text,
-    p_user_id text
+    p_user_id text,
+    p_version int
 ) returns forms as $$
 declare
     v_form forms;
@@ -170,6 +393,18 @@ begin
         raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
+    -- an older version is returned with the structure it had at the time
+    if p_version is not null and p_version != v_form.version then
+        select fv.structure into v_form.structure from form_versions fv
+        where fv.form = p_id and fv.version = p_version;
+
+        if not found then
+            raise exception 'Form version not found.' using hint = 'not-found';
+        end if;
+
+        v_form.version := p_version;
+    end if;
+
     return v_form;
 end;
 $$ language plpgsql;
@@ -178,15 +413,40 @@ create or replace function update_form_by_id(
     p_id text, p_user_id text, p_slug text, p_title text, p_description text,
     p_structure text, p_live boolean, p_opens timestamptz, p_closes timestamptz,
     p_anonymous boolean, p_max_responses int, p_individual_limit int,
-    p_editable_responses boolean
+    p_editable_responses boolean, p_edit_deadline timestamptz, p_edit_window int,
+    p_quiz boolean
 ) returns forms as $$
 declare
     v_form forms;
+    v_before forms;
 begin
     if not has_form_permission(p_user_id, p_id, 'edit'::permission_role) then
         raise exception 'You do not have permission to edit this form.' using hint = 'forbidden';
     end if;
 
+    if is_reserved_slug(p_slug) then
+        raise exception 'Slug % is the name of a route and cannot be used.', p_slug using hint = 'reserved-slug';
+    end if;
+
+    -- locked so that concurrent edits to the structure number their versions
+    -- one after the other
+    select * into v_form from forms where id = p_id for update;
+    v_before := v_form;
+
+    -- responses cannot be linked to or unlinked from their respondents later
+    if p_anonymous is not null and p_anonymous is distinct from v_form.anonymous and
+        exists (select 1 from responses r where r.form = p_id) then
+        raise exception 'Anonymity cannot be changed once a form has responses.' using hint = 'already-exists';
+    end if;
+
+    -- versions are immutable, so changing the structure creates a new one
+    if p_structure is not null and p_structure != v_form.structure then
+        insert into form_versions (form, version, structure, author)
+        values (p_id, v_form.version + 1, p_structure, p_user_id);
+
+        update forms set version = version + 1 where id = p_id;
+    end if;
+
     update forms set
         slug = coalesce(p_slug, slug), title = coalesce(p_title, title),
         description = coalesce(p_description, description),
@@ -195,555 +455,1833 @@ begin
         anonymous = coalesce(p_anonymous, anonymous),
         max_responses = coalesce(p_max_responses, max_responses),
         individual_limit = coalesce(p_individual_limit, individual_limit),
-        editable_responses = coalesce(p_editable_responses, editable_responses)
+        editable_responses = coalesce(p_editable_responses, editable_responses),
+        edit_deadline = coalesce(p_edit_deadline, edit_deadline),
+        edit_window = coalesce(p_edit_window, edit_window),
+        quiz = coalesce(p_quiz, quiz)
     where id = p_id
     returning * into v_form;
 
-    return v_form;
-end;
-$$ language plpgsql;
-
-create or replace function delete_form_by_id(
-    p_id text,
-    p_user_id text
-) returns void as $$
-begin
-    if not has_form_permission(p_user_id, p_id, 'manage'::permission_role) then
-        raise exception 'You do not have permission to delete this form.' using hint = 'forbidden';
-    end if;
-
-    delete from forms where id = p_id;
-end;
-$$ language plpgsql;
-
-create or replace function list_permissions_for_form(
-    p_form_id text,
-    p_user_id text
-) returns setof form_permissions as $$
-begin
-    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
-        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
-    end if;
+    perform record_audit(
+        p_user_id, 'form.update', p_id, null, p_id,
+        to_jsonb(v_before) - 'structure', to_jsonb(v_form) - 'structure'
+    );
 
-    return query select * from form_permissions where form = p_form_id;
+    return v_form;
 end;
 $$ language plpgsql;
 
-create or replace function grant_permission_on_form(
+-- offering the form to another user replaces any earlier offer
+create or replace function offer_form_transfer(
     p_form_id text,
     p_user_id text,
-    p_target_user text,
-    p_target_group text,
-    p_role permission_role
-) returns setof form_permissions as $$
+    p_target_user text
+) returns form_transfers as $$
 declare
     v_target_user_id text;
+    v_transfer form_transfers;
 begin
     if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
-        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
     select u.id into v_target_user_id from users u where u.email = p_target_user;
-    if not found and p_target_user is not null then
+    if not found then
         raise exception 'User with email % does not exist.', p_target_user using hint = 'not-found';
     end if;
 
-    return query select * from form_permissions
-    where form = p_form_id and role = p_role
-      and "user" is not distinct from v_target_user_id
-      and "group" is not distinct from p_target_group;
-
-    if not found then
-        return query insert into form_permissions (form, role, "user", "group")
-        values (p_form_id, p_role, v_target_user_id, p_target_group) returning *;
+    if v_target_user_id = (select f.owner from forms f where f.id = p_form_id) then
+        raise exception 'The user already owns this form.' using hint = 'already-exists';
     end if;
-end;
-$$ language plpgsql;
 
-create or replace function revoke_permission_by_id(
-    p_form_id text, p_user_id text, p_permission_id text
-) returns void as $$
-begin
-    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
-        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
-    end if;
+    insert into form_transfers (form, recipient, sender)
+    values (p_form_id, v_target_user_id, p_user_id)
+    on conflict (form) do update set
+        recipient = excluded.recipient,
+        sender = excluded.sender,
+        created = now()
+    returning * into v_transfer;
+
+    perform record_audit(
+        p_user_id, 'form.transfer.offer', p_form_id, null, p_form_id,
+        null, to_jsonb(v_transfer)
+    );
 
-    delete from form_permissions where id = p_permission_id;
+    return v_transfer;
 end;
 $$ language plpgsql;
 
-create or replace function list_groups_for_user(
-    p_user_id text,
-    p_owner_email text,
-    p_filter_type group_type,
-    p_sort_by text,
-    p_order text,
-    p_limit int,
-    p_offset int
-) returns setof group_with_details as $$
+create or replace function list_transfers_for_user(
+    p_user_id text
+) returns setof form_transfers as $$
 begin
-    return query with group_details as (
-        select g.*, d.domain, array_agg(m."user" order by m."user")
-        filter (where m."user" is not null) as members from groups g
-        left join group_domain_rules d on g.id = d."group"
-        left join group_list_members m on g.id = m."group"
-        where (p_owner_email is null or owner = (
-            select id from users where email = p_owner_email
-        )) group by g.id, d.domain
-    ) select * from group_details where owner = p_user_id or (
-        p_user_id = any(members) or domain = (
-            select substring(email from '@(.*)$') from users where id = p_user_id
-        )
-    ) and (p_filter_type is null or type = p_filter_type) order by
-        case when p_sort_by = 'created' and p_order = 'asc' then id end asc,
-        case when p_sort_by = 'created' and p_order = 'desc' then id end desc,
-        case when p_sort_by = 'name' and p_order = 'asc' then name end asc,
-        case when p_sort_by = 'name' and p_order = 'desc' then name end desc,
-        case when p_sort_by = 'type' and p_order = 'asc' then type end asc,
-        case when p_sort_by = 'type' and p_order = 'desc' then type end desc
-    limit p_limit offset p_offset;
+    return query select * from form_transfers where recipient = p_user_id
+    order by created desc;
 end;
 $$ language plpgsql;
 
-create or replace function count_groups_for_user(p_user_id text)
-returns bigint as $$
+-- offers can be withdrawn by managers of the form, or declined by recipients
+create or replace function cancel_form_transfer(
+    p_form_id text,
+    p_user_id text
+) returns void as $$
 declare
-    v_count bigint;
+    v_before jsonb;
 begin
-    select count(distinct id) into v_count from groups where owner = p_user_id;
+    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) and
+        not exists (
+            select 1 from form_transfers ft
+            where ft.form = p_form_id and ft.recipient = p_user_id
+        ) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
 
-    return v_count;
+    with deleted as (
+        delete from form_transfers where form = p_form_id returning *
+    ) select to_jsonb(d) into v_before from deleted d;
+
+    if v_before is not null then
+        perform record_audit(
+            p_user_id, 'form.transfer.cancel', p_form_id, null, p_form_id, v_before, null
+        );
+    end if;
 end;
 $$ language plpgsql;
 
-create or replace function create_group_of_type(
-    p_owner_id text,
-    p_name text,
-    p_description text,
-    p_type group_type,
-    p_domain text,
-    p_members text[]
-) returns group_with_details as $$
+-- the previous owner keeps the manage role, as an ordinary grant, and the
+-- earlier url of the form keeps leading to it. the slug is given a suffix if
+-- the new owner already has a form with the same slug
+create or replace function move_form(
+    p_form_id text,
+    p_owner_id text
+) returns forms as $$
 declare
-    v_group_id text;
-    v_missing_emails text[];
-    v_group group_with_details;
+    v_form forms;
+    v_slug text;
+    v_suffix int := 1;
 begin
-    insert into groups (owner, name, description, type)
-    values (p_owner_id, p_name, p_description, p_type)
-    returning groups.id into v_group_id;
+    select * into v_form from forms where id = p_form_id for update;
 
-    if p_type = 'domain' and p_domain is not null then
-        insert into group_domain_rules ("group", domain)
-        values (v_group_id, p_domain);
-    end if;
+    insert into form_redirects (handle, slug, form)
+    select u.handle, v_form.slug, v_form.id from users u where u.id = v_form.owner
+    on conflict (handle, slug) do update set form = excluded.form;
 
-    if p_type = 'list' and p_members is not null then
-        select array_agg(i_email) into v_missing_emails
-        from unnest(p_members) as i_email
-        left join users u on u.email = i_email where u.id is null;
+    v_slug := v_form.slug;
+    while exists (select 1 from forms f where f.owner = p_owner_id and f.slug = v_slug) loop
+        v_suffix := v_suffix + 1;
+        v_slug := v_form.slug || '-' || v_suffix;
+    end loop;
 
-        if v_missing_emails is not null then
-            raise exception 'Could not create group with users that do not exist: %',
-                array_to_string(v_missing_emails, ', ') using hint = 'not-found';
-        end if;
+    update forms set owner = p_owner_id, slug = v_slug where id = p_form_id
+    returning * into v_form;
 
-        insert into group_list_members ("group", "user")
-        select v_group_id, u.id from unnest(p_members) as i_email
-        join users u on u.email = i_email on conflict do nothing;
-    end if;
+    insert into form_permissions (form, role, "user")
+    values (p_form_id, 'manage', p_owner_id)
+    on conflict (form, "user") where "user" is not null do update set
+        role = 'manage',
+        starts = null,
+        expires = null;
 
-    select g.*, d.domain, array_agg(m."user" order by m."user")
-    filter (where m."user" is not null) as members into v_group from groups g
-    left join group_domain_rules d on g.id = d."group"
-    left join group_list_members m on g.id = m."group"
-    where g.id = v_group_id
-    group by g.id, g.owner, g.name, g.description, g.type, d.domain;
+    delete from form_transfers where form = p_form_id;
 
-    return v_group;
+    return v_form;
 end;
 $$ language plpgsql;
 
-create or replace function get_group_by_id(
-    p_id text,
+create or replace function accept_form_transfer(
+    p_form_id text,
     p_user_id text
-) returns group_with_details as $$
+) returns forms as $$
 declare
-    v_group group_with_details;
+    v_form forms;
+    v_before forms;
+begin
+    perform 1 from form_transfers ft
+    where ft.form = p_form_id and ft.recipient = p_user_id
+    for update;
+
+    if not found then
+        raise exception 'Transfer not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    select * into v_before from forms where id = p_form_id;
+    v_form := move_form(p_form_id, p_user_id);
+
+    perform record_audit(
+        p_user_id, 'form.transfer.accept', p_form_id, null, p_form_id,
+        to_jsonb(v_before) - 'structure', to_jsonb(v_form) - 'structure'
+    );
+
+    return v_form;
+end;
+$$ language plpgsql;
+
+create or replace function release_scores_for_form(
+    p_id text,
+    p_user_id text,
+    p_released boolean
+) returns forms as $$
+declare
+    v_form forms;
+begin
+    if not has_form_permission(p_user_id, p_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to release scores for this form.' using hint = 'forbidden';
+    end if;
+
+    update forms set scores_released = p_released
+    where id = p_id
+    returning * into v_form;
+
+    perform record_audit(
+        p_user_id, 'form.scores', p_id, null, p_id,
+        null, jsonb_build_object('scores_released', p_released)
+    );
+
+    return v_form;
+end;
+$$ language plpgsql;
+
+create or replace function delete_form_by_id(
+    p_id text,
+    p_user_id text
+) returns void as $$
+declare
+    v_before jsonb;
+begin
+    if not has_form_permission(p_user_id, p_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to delete this form.' using hint = 'forbidden';
+    end if;
+
+    with deleted as (
+        delete from forms where id = p_id returning *
+    ) select to_jsonb(d) - 'structure' into v_before from deleted d;
+
+    perform record_audit(p_user_id, 'form.delete', p_id, null, p_id, v_before, null);
+end;
+$$ language plpgsql;
+
+create or replace function list_versions_for_form(
+    p_form_id text,
+    p_user_id text,
+    p_limit int,
+    p_offset int
+) returns setof form_versions as $$
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'edit'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    return query select * from form_versions fv where fv.form = p_form_id
+    order by fv.version desc limit p_limit offset p_offset;
+end;
+$$ language plpgsql;
+
+create or replace function count_versions_for_form(
+    p_form_id text,
+    p_user_id text
+) returns bigint as $$
+declare
+    v_count bigint;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'edit'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    select count(*) into v_count from form_versions fv where fv.form = p_form_id;
+
+    return v_count;
+end;
+$$ language plpgsql;
+
+create or replace function get_version_for_form(
+    p_form_id text,
+    p_user_id text,
+    p_version int
+) returns form_versions as $$
+declare
+    v_version form_versions;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'edit'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    select * into v_version from form_versions fv
+    where fv.form = p_form_id and fv.version = p_version;
+
+    if not found then
+        raise exception 'Form version not found.' using hint = 'not-found';
+    end if;
+
+    return v_version;
+end;
+$$ language plpgsql;
+
+create or replace function list_permissions_for_form(
+    p_form_id text,
+    p_user_id text
+) returns setof form_permissions as $$
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
+    end if;
+
+    return query select * from form_permissions where form = p_form_id;
+end;
+$$ language plpgsql;
+
+create or replace function grant_permission_on_form(
+    p_form_id text,
+    p_user_id text,
+    p_target_user text,
+    p_target_group text,
+    p_anyone boolean,
+    p_role permission_role,
+    p_starts timestamptz,
+    p_expires timestamptz
+) returns setof form_permissions as $$
+declare
+    v_target_user_id text;
+    v_before jsonb;
+    v_permission form_permissions;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
+    end if;
+
+    select u.id into v_target_user_id from users u where u.email = p_target_user;
+    if not found and p_target_user is not null then
+        raise exception 'User with email % does not exist.', p_target_user using hint = 'not-found';
+    end if;
+
+    if v_target_user_id = (select f.owner from forms f where f.id = p_form_id) then
+        raise exception 'The permissions of the owner cannot be changed.' using hint = 'forbidden';
+    end if;
+
+    select to_jsonb(fp) into v_before from form_permissions fp
+    where fp.form = p_form_id
+      and fp."user" is not distinct from v_target_user_id
+      and fp."group" is not distinct from p_target_group
+      and fp.anyone = p_anyone;
+
+    -- each grantee has a single role, which granting again replaces
+    update form_permissions set
+        role = p_role,
+        starts = p_starts,
+        expires = p_expires
+    where form = p_form_id
+      and "user" is not distinct from v_target_user_id
+      and "group" is not distinct from p_target_group
+      and anyone = p_anyone
+    returning * into v_permission;
+
+    if not found then
+        insert into form_permissions (
+            form, role, "user", "group", anyone, starts, expires
+        ) values (
+            p_form_id, p_role, v_target_user_id, p_target_group, p_anyone, p_starts, p_expires
+        ) returning * into v_permission;
+    end if;
+
+    perform record_audit(
+        p_user_id, 'permission.grant', p_form_id, null, v_permission.id,
+        v_before, to_jsonb(v_permission)
+    );
+
+    return next v_permission;
+end;
+$$ language plpgsql;
+
+-- grants a role to an email that no user has logged in with yet. inviting
+-- the same email again replaces the role, like granting it does
+create or replace function invite_to_form(
+    p_form_id text,
+    p_user_id text,
+    p_email text,
+    p_role permission_role,
+    p_starts timestamptz,
+    p_expires timestamptz
+) returns pending_permissions as $$
+declare
+    v_invite pending_permissions;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
+    end if;
+
+    if exists (select 1 from users u where u.email = p_email) then
+        raise exception 'User with email % already exists.', p_email using hint = 'already-exists';
+    end if;
+
+    insert into pending_permissions (form, email, role, starts, expires, inviter)
+    values (p_form_id, p_email, p_role, p_starts, p_expires, p_user_id)
+    on conflict (form, email) do update set
+        role = excluded.role,
+        starts = excluded.starts,
+        expires = excluded.expires,
+        inviter = excluded.inviter
+    returning * into v_invite;
+
+    perform record_audit(
+        p_user_id, 'permission.invite', p_form_id, null, v_invite.id,
+        null, to_jsonb(v_invite)
+    );
+
+    return v_invite;
+end;
+$$ language plpgsql;
+
+create or replace function list_invites_for_form(
+    p_form_id text,
+    p_user_id text
+) returns setof pending_permissions as $$
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
+    end if;
+
+    return query select * from pending_permissions where form = p_form_id
+    order by created;
+end;
+$$ language plpgsql;
+
+create or replace function cancel_invite_to_form(
+    p_form_id text,
+    p_user_id text,
+    p_invite_id text
+) returns void as $$
+declare
+    v_before jsonb;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
+    end if;
+
+    with deleted as (
+        delete from pending_permissions where id = p_invite_id and form = p_form_id
+        returning *
+    ) select to_jsonb(d) into v_before from deleted d;
+
+    if v_before is not null then
+        perform record_audit(
+            p_user_id, 'permission.invite.cancel', p_form_id, null, p_invite_id, v_before, null
+        );
+    end if;
+end;
+$$ language plpgsql;
+
+create or replace function revoke_permission_by_id(
+    p_form_id text, p_user_id text, p_permission_id text
+) returns void as $$
+declare
+    v_before jsonb;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
+        raise exception 'You do not have permission to manage this form.' using hint = 'forbidden';
+    end if;
+
+    if exists (
+        select 1 from form_permissions fp join forms f on f.id = fp.form
+        where fp.id = p_permission_id and fp."user" = f.owner
+    ) then
+        raise exception 'The permissions of the owner cannot be changed.' using hint = 'forbidden';
+    end if;
+
+    with deleted as (
+        delete from form_permissions where id = p_permission_id and form = p_form_id
+        returning *
+    ) select to_jsonb(d) into v_before from deleted d;
+
+    if v_before is not null then
+        perform record_audit(
+            p_user_id, 'permission.revoke', p_form_id, null, p_permission_id, v_before, null
+        );
+    end if;
+end;
+$$ language plpgsql;
+
+-- whether a user is a member of a group itself, leaving out nested groups
+create or replace function is_direct_group_member(
+    p_group_id text,
+    p_user_id text
+) returns boolean as $$
+begin
+    return exists (
+        select 1 from group_list_members glm
+        where glm."group" = p_group_id and glm."user" = p_user_id
+
+        union all
+
+        select 1 from group_domain_rules gdr
+        where gdr."group" = p_group_id and gdr.domain = (
+            select substring(email from '@(.*)$') from users where id = p_user_id
+        )
+    );
+end;
+$$ language plpgsql;
+
+-- the members of a group are its own members and those of the groups included
+-- in it, less those of the groups excluded from it. groups are never nested in
+-- themselves, so this always ends
+create or replace function is_group_member(
+    p_group_id text,
+    p_user_id text
+) returns boolean as $$
+begin
+    if not is_direct_group_member(p_group_id, p_user_id) and not exists (
+        select 1 from group_subgroups gs
+        where gs."group" = p_group_id and not gs.exclude and
+            is_group_member(gs.subgroup, p_user_id)
+    ) then
+        return false;
+    end if;
+
+    return not exists (
+        select 1 from group_subgroups gs
+        where gs."group" = p_group_id and gs.exclude and
+            is_group_member(gs.subgroup, p_user_id)
+    );
+end;
+$$ language plpgsql;
+
+-- only the groups a user is a direct member of, and those they are included
+-- in, can have the user as a member, so only those are checked
+create or replace function list_member_groups_of_user(
+    p_user_id text
+) returns setof text as $$
+begin
+    return query with recursive candidates(id) as (
+        select glm."group" from group_list_members glm where glm."user" = p_user_id
+
+        union
+
+        select gdr."group" from group_domain_rules gdr where gdr.domain = (
+            select substring(email from '@(.*)$') from users where id = p_user_id
+        )
+
+        union
+
+        select gs."group" from group_subgroups gs
+        join candidates c on gs.subgroup = c.id
+        where not gs.exclude
+    ) select c.id from candidates c where is_group_member(c.id, p_user_id);
+end;
+$$ language plpgsql;
+
+create or replace function list_groups_for_user(
+    p_user_id text,
+    p_owner_email text,
+    p_filter_type group_type,
+    p_sort_by text,
+    p_order text,
+    p_limit int,
+    p_offset int
+) returns setof group_with_details as $$
+begin
+    return query with group_details as (
+        select g.*, d.domain, array_agg(m."user" order by m."user")
+        filter (where m."user" is not null) as members from groups g
+        left join group_domain_rules d on g.id = d."group"
+        left join group_list_members m on g.id = m."group"
+        where (p_owner_email is null or owner = (
+            select id from users where email = p_owner_email
+        )) group by g.id, d.domain
+    ) select * from group_details where owner = p_user_id or (
+        id in (select gm."group" from group_managers gm where gm."user" = p_user_id) or
+        id in (select list_member_groups_of_user(p_user_id))
+    ) and (p_filter_type is null or type = p_filter_type) order by
+        case when p_sort_by = 'created' and p_order = 'asc' then id end asc,
+        case when p_sort_by = 'created' and p_order = 'desc' then id end desc,
+        case when p_sort_by = 'name' and p_order = 'asc' then name end asc,
+        case when p_sort_by = 'name' and p_order = 'desc' then name end desc,
+        case when p_sort_by = 'type' and p_order = 'asc' then type end asc,
+        case when p_sort_by = 'type' and p_order = 'desc' then type end desc
+    limit p_limit offset p_offset;
+end;
+$$ language plpgsql;
+
+create or replace function count_groups_for_user(p_user_id text)
+returns bigint as $$
+declare
+    v_count bigint;
+begin
+    select count(distinct id) into v_count from groups where owner = p_user_id;
+
+    return v_count;
+end;
+$$ language plpgsql;
+
+create or replace function create_group_of_type(
+    p_owner_id text,
+    p_name text,
+    p_description text,
+    p_type group_type,
+    p_domain text,
+    p_members text[]
+) returns group_with_details as $$
+declare
+    v_group_id text;
+    v_group group_with_details;
+begin
+    insert into groups (owner, name, description, type)
+    values (p_owner_id, p_name, p_description, p_type)
+    returning groups.id into v_group_id;
+
+    if p_type = 'domain' and p_domain is not null then
+        insert into group_domain_rules ("group", domain)
+        values (v_group_id, p_domain);
+    end if;
+
+    if p_type = 'list' and p_members is not null then
+        insert into group_list_members ("group", "user")
+        select v_group_id, u.id from unnest(p_members) as i_email
+        join users u on u.email = i_email on conflict do nothing;
+
+        -- members who have never logged in join once they do
+        insert into pending_group_members ("group", email, inviter)
+        select v_group_id, i_email, p_owner_id from unnest(p_members) as i_email
+        left join users u on u.email = i_email where u.id is null
+        on conflict do nothing;
+    end if;
+
+    select g.*, d.domain, array_agg(m."user" order by m."user")
+    filter (where m."user" is not null) as members into v_group from groups g
+    left join group_domain_rules d on g.id = d."group"
+    left join group_list_members m on g.id = m."group"
+    where g.id = v_group_id
+    group by g.id, g.owner, g.name, g.description, g.type, d.domain;
+
+    perform record_audit(
+        p_owner_id, 'group.create', null, v_group_id, v_group_id, null, to_jsonb(v_group)
+    );
+
+    return v_group;
+end;
+$$ language plpgsql;
+
+create or replace function get_group_by_id(
+    p_id text,
+    p_user_id text
+) returns group_with_details as $$
+declare
+    v_group group_with_details;
 begin
     if not has_group_permission(p_user_id, p_id, null) then
         raise exception 'Group not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    select g.*, d.domain, array_agg(m."user" order by m."user")
-    filter (where m."user" is not null) as members into v_group from groups g
-    left join group_domain_rules d on g.id = d."group"
-    left join group_list_members m on g.id = m."group"
-    where g.id = p_id and g.owner = p_user_id
-    group by g.id, g.owner, g.name, g.description, g.type, d.domain;
+    select g.*, d.domain, array_agg(m."user" order by m."user")
+    filter (where m."user" is not null) as members into v_group from groups g
+    left join group_domain_rules d on g.id = d."group"
+    left join group_list_members m on g.id = m."group"
+    where g.id = p_id
+    group by g.id, g.owner, g.name, g.description, g.type, d.domain;
+
+    if not found then
+        raise exception 'Group not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    return v_group;
+end;
+$$ language plpgsql;
+
+create or replace function update_group_by_id(
+    p_id text,
+    p_user_id text,
+    p_name text,
+    p_description text
+) returns group_with_details as $$
+declare
+    v_group group_with_details;
+    v_before jsonb;
+begin
+    if not has_group_permission(p_user_id, p_id, null) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    select to_jsonb(g) into v_before from groups g where g.id = p_id;
+
+    update groups set
+        name = coalesce(p_name, name),
+        description = coalesce(p_description, description)
+    where id = p_id
+    returning * into v_group;
+
+    perform record_audit(
+        p_user_id, 'group.update', null, p_id, p_id,
+        v_before, (select to_jsonb(g) from groups g where g.id = p_id)
+    );
+
+    return v_group;
+end;
+$$ language plpgsql;
+
+create or replace function update_domain_for_group(
+    p_id text,
+    p_user_id text,
+    p_domain text
+) returns void as $$
+declare
+    v_before text;
+begin
+    if not has_group_permission(p_user_id, p_id, 'domain'::group_type) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    select gdr.domain into v_before from group_domain_rules gdr where gdr."group" = p_id;
+
+    update group_domain_rules set domain = p_domain where "group" = p_id;
+
+    perform record_audit(
+        p_user_id, 'group.domain', null, p_id, p_id,
+        jsonb_build_object('domain', v_before), jsonb_build_object('domain', p_domain)
+    );
+end;
+$$ language plpgsql;
+
+create or replace function delete_group_by_id(
+    p_id text,
+    p_user_id text
+) returns void as $$
+declare
+    v_before jsonb;
+begin
+    if not has_group_role(p_user_id, p_id, 'owner'::group_role) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    with deleted as (
+        delete from groups where id = p_id returning *
+    ) select to_jsonb(d) into v_before from deleted d;
+
+    perform record_audit(p_user_id, 'group.delete', null, p_id, p_id, v_before, null);
+end;
+$$ language plpgsql;
+
+create or replace function add_group_member_by_email(
+    p_group_id text,
+    p_user_id text,
+    p_target_user text
+) returns void as $$
+declare
+    v_target_user_id text;
+begin
+    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    select u.id into v_target_user_id from users u where u.email = p_target_user;
+    if not found then
+        raise exception 'User with email % does not exist.', p_target_user using hint = 'not-found';
+    end if;
+
+    insert into group_list_members ("group", "user")
+    values (p_group_id, v_target_user_id) on conflict do nothing;
+
+    perform record_audit(
+        p_user_id, 'group.member.add', null, p_group_id, v_target_user_id,
+        null, jsonb_build_object('user', v_target_user_id)
+    );
+end;
+$$ language plpgsql;
+
+-- adds an email that no user has logged in with yet to a group, once they do.
+-- inviting the same email again only changes who invited them
+create or replace function invite_to_group(
+    p_group_id text,
+    p_user_id text,
+    p_email text
+) returns pending_group_members as $$
+declare
+    v_invite pending_group_members;
+begin
+    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    if exists (select 1 from users u where u.email = p_email) then
+        raise exception 'User with email % already exists.', p_email using hint = 'already-exists';
+    end if;
+
+    insert into pending_group_members ("group", email, inviter)
+    values (p_group_id, p_email, p_user_id)
+    on conflict ("group", email) do update set inviter = excluded.inviter
+    returning * into v_invite;
+
+    perform record_audit(
+        p_user_id, 'group.member.invite', null, p_group_id, v_invite.id,
+        null, to_jsonb(v_invite)
+    );
+
+    return v_invite;
+end;
+$$ language plpgsql;
+
+create or replace function list_invites_for_group(
+    p_group_id text,
+    p_user_id text
+) returns setof pending_group_members as $$
+begin
+    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    return query select * from pending_group_members where "group" = p_group_id
+    order by created;
+end;
+$$ language plpgsql;
+
+create or replace function cancel_invite_to_group(
+    p_group_id text,
+    p_user_id text,
+    p_invite_id text
+) returns void as $$
+declare
+    v_before jsonb;
+begin
+    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    with deleted as (
+        delete from pending_group_members where id = p_invite_id and "group" = p_group_id
+        returning *
+    ) select to_jsonb(d) into v_before from deleted d;
+
+    if v_before is not null then
+        perform record_audit(
+            p_user_id, 'group.member.invite.cancel', null, p_group_id, p_invite_id, v_before, null
+        );
+    end if;
+end;
+$$ language plpgsql;
+
+create or replace function remove_group_member_by_id(
+    p_group_id text,
+    p_user_id text,
+    p_target_user_id text
+) returns void as $$
+begin
+    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    delete from group_list_members
+    where "group" = p_group_id and "user" = p_target_user_id;
+
+    if found then
+        perform record_audit(
+            p_user_id, 'group.member.remove', null, p_group_id, p_target_user_id,
+            jsonb_build_object('user', p_target_user_id), null
+        );
+    end if;
+end;
+$$ language plpgsql;
+
+create or replace function list_managers_for_group(
+    p_group_id text,
+    p_user_id text
+) returns setof users as $$
+begin
+    if not has_group_permission(p_user_id, p_group_id, null) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    return query select u.* from group_managers gm
+    join users u on u.id = gm."user"
+    where gm."group" = p_group_id order by u.name;
+end;
+$$ language plpgsql;
+
+create or replace function add_group_manager_by_email(
+    p_group_id text,
+    p_user_id text,
+    p_target_user text
+) returns users as $$
+declare
+    v_target_user users;
+begin
+    if not has_group_role(p_user_id, p_group_id, 'owner'::group_role) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    select * into v_target_user from users u where u.email = p_target_user;
+    if not found then
+        raise exception 'User with email % does not exist.', p_target_user using hint = 'not-found';
+    end if;
+
+    if v_target_user.id = p_user_id then
+        raise exception 'The owner of a group cannot be made its manager.' using hint = 'already-exists';
+    end if;
+
+    insert into group_managers ("group", "user")
+    values (p_group_id, v_target_user.id) on conflict do nothing;
+
+    perform record_audit(
+        p_user_id, 'group.manager.add', null, p_group_id, v_target_user.id,
+        null, jsonb_build_object('user', v_target_user.id)
+    );
+
+    return v_target_user;
+end;
+$$ language plpgsql;
+
+-- managers can step down by removing themselves
+create or replace function remove_group_manager_by_id(
+    p_group_id text,
+    p_user_id text,
+    p_target_user_id text
+) returns void as $$
+begin
+    if not has_group_role(p_user_id, p_group_id, 'owner'::group_role) and (
+        p_user_id <> p_target_user_id or
+        not has_group_role(p_user_id, p_group_id, 'manager'::group_role)
+    ) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    delete from group_managers
+    where "group" = p_group_id and "user" = p_target_user_id;
+
+    if found then
+        perform record_audit(
+            p_user_id, 'group.manager.remove', null, p_group_id, p_target_user_id,
+            jsonb_build_object('user', p_target_user_id), null
+        );
+    end if;
+end;
+$$ language plpgsql;
+
+-- the previous owner stays on as a manager
+create or replace function move_group(
+    p_group_id text,
+    p_owner_id text
+) returns groups as $$
+declare
+    v_previous_owner text;
+    v_group groups;
+begin
+    select owner into v_previous_owner from groups where id = p_group_id for update;
+
+    update groups set owner = p_owner_id where id = p_group_id
+    returning * into v_group;
+
+    delete from group_managers where "group" = p_group_id and "user" = p_owner_id;
+    if p_owner_id <> v_previous_owner then
+        insert into group_managers ("group", "user") values (p_group_id, v_previous_owner)
+        on conflict do nothing;
+    end if;
+
+    return v_group;
+end;
+$$ language plpgsql;
+
+create or replace function transfer_group_by_id(
+    p_id text,
+    p_user_id text,
+    p_target_user text
+) returns groups as $$
+declare
+    v_target_user_id text;
+    v_group groups;
+begin
+    if not has_group_role(p_user_id, p_id, 'owner'::group_role) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    select u.id into v_target_user_id from users u where u.email = p_target_user;
+    if not found then
+        raise exception 'User with email % does not exist.', p_target_user using hint = 'not-found';
+    end if;
+
+    v_group := move_group(p_id, v_target_user_id);
+
+    perform record_audit(
+        p_user_id, 'group.transfer', null, p_id, p_id,
+        jsonb_build_object('owner', p_user_id), jsonb_build_object('owner', v_target_user_id)
+    );
+
+    return v_group;
+end;
+$$ language plpgsql;
+
+create or replace function list_subgroups_for_group(
+    p_group_id text,
+    p_user_id text
+) returns setof group_subgroups as $$
+begin
+    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    return query select * from group_subgroups where "group" = p_group_id
+    order by subgroup;
+end;
+$$ language plpgsql;
+
+-- nesting a group again replaces whether it is excluded
+create or replace function add_subgroup_to_group(
+    p_group_id text,
+    p_user_id text,
+    p_subgroup_id text,
+    p_exclude boolean
+) returns group_subgroups as $$
+declare
+    v_subgroup group_subgroups;
+    v_before jsonb;
+begin
+    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    -- nesting a group changes who is a member of the other, so the user must
+    -- manage both of them
+    if not has_group_permission(p_user_id, p_subgroup_id, null) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    -- nesting groups one at a time, so that concurrent changes cannot make a
+    -- cycle between them
+    perform pg_advisory_xact_lock(hashtext('group_subgroups'));
+
+    if p_subgroup_id = p_group_id or exists (
+        with recursive descendants(id) as (
+            select gs.subgroup from group_subgroups gs where gs."group" = p_subgroup_id
+            union
+            select gs.subgroup from group_subgroups gs
+            join descendants d on gs."group" = d.id
+        ) select 1 from descendants d where d.id = p_group_id
+    ) then
+        raise exception 'A group cannot be nested in itself.' using hint = 'group-cycle';
+    end if;
+
+    select to_jsonb(gs) into v_before from group_subgroups gs
+    where gs."group" = p_group_id and gs.subgroup = p_subgroup_id;
+
+    insert into group_subgroups ("group", subgroup, exclude)
+    values (p_group_id, p_subgroup_id, p_exclude)
+    on conflict ("group", subgroup) do update set exclude = excluded.exclude
+    returning * into v_subgroup;
+
+    perform record_audit(
+        p_user_id, 'group.subgroup.add', null, p_group_id, p_subgroup_id,
+        v_before, to_jsonb(v_subgroup)
+    );
+
+    return v_subgroup;
+end;
+$$ language plpgsql;
+
+create or replace function remove_subgroup_from_group(
+    p_group_id text,
+    p_user_id text,
+    p_subgroup_id text
+) returns void as $$
+declare
+    v_before jsonb;
+begin
+    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
+        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    end if;
+
+    with deleted as (
+        delete from group_subgroups
+        where "group" = p_group_id and subgroup = p_subgroup_id
+        returning *
+    ) select to_jsonb(d) into v_before from deleted d;
+
+    if v_before is not null then
+        perform record_audit(
+            p_user_id, 'group.subgroup.remove', null, p_group_id, p_subgroup_id, v_before, null
+        );
+    end if;
+end;
+$$ language plpgsql;
+
+create or replace function list_comments_for_form(
+    p_form_id text,
+    p_user_id text
+) returns setof comments as $$
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'comment'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    return query select * from comments where form = p_form_id
+    order by modified desc;
+end;
+$$ language plpgsql;
+
+create or replace function create_comment_on_form(
+    p_form_id text, p_user_id text,
+    p_body text, p_element text, p_parent text
+) returns comments as $$
+declare
+    v_comment comments;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'comment'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    insert into comments (form, commenter, body, element, parent)
+    values (p_form_id, p_user_id, p_body, p_element, p_parent)
+    returning * into v_comment;
+
+    perform record_audit(
+        p_user_id, 'comment.create', p_form_id, null, v_comment.id,
+        null, to_jsonb(v_comment)
+    );
+
+    return v_comment;
+end;
+$$ language plpgsql;
+
+create or replace function update_comment_by_id(
+    p_id text, p_form_id text, p_user_id text,
+    p_body text, p_state comment_state
+) returns comments as $$
+declare
+    v_comment comments;
+    v_before jsonb;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'comment'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
 
+    select * into v_comment from comments where id = p_id and form = p_form_id;
     if not found then
-        raise exception 'Group not found or you do not have permission do this.' using hint = 'forbidden';
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    if v_comment.commenter != p_user_id then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    v_before := to_jsonb(v_comment);
+
+    update comments set
+        body = coalesce(p_body, body),
+        state = coalesce(p_state, state)
+    where id = p_id returning * into v_comment;
+
+    perform record_audit(
+        p_user_id, 'comment.update', p_form_id, null, p_id,
+        v_before, to_jsonb(v_comment)
+    );
+
+    return v_comment;
+end;
+$$ language plpgsql;
+
+create or replace function delete_comment_by_id(
+    p_id text, p_form_id text, p_user_id text
+) returns void as $$
+declare
+    v_comment comments;
+begin
+    select * into v_comment from comments where id = p_id and form = p_form_id;
+
+    if not found then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    if v_comment.commenter != p_user_id and not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    delete from comments where id = p_id;
+
+    perform record_audit(
+        p_user_id, 'comment.delete', p_form_id, null, p_id, to_jsonb(v_comment), null
+    );
+end;
+$$ language plpgsql;
+
+-- responses to anonymous forms, and those by guests, are not linked to their
+-- respondent, who instead proves they own the response with the key they were
+-- given when starting it, or their guest token
+create or replace function owns_response(
+    p_response responses,
+    p_user_id text,
+    p_key text
+) returns boolean as $$
+begin
+    if p_response.respondent is not null then
+        return p_response.respondent = p_user_id;
+    end if;
+
+    return p_key is not null and p_response.key_hash is not null and
+        p_response.key_hash = encode(digest(p_key, 'sha256'), 'hex');
+end;
+$$ language plpgsql;
+
+-- submitted responses can only be changed if the form allows editing them,
+-- until the edit deadline and within the edit window after submission
+create or replace function check_response_editable(
+    p_response responses
+) returns void as $$
+declare
+    v_form forms;
+begin
+    if p_response.submitted is null then
+        return;
+    end if;
+
+    select * into v_form from forms f where f.id = p_response.form;
+
+    if not v_form.editable_responses then
+        raise exception 'Responses to this form cannot be edited once submitted.' using hint = 'response-locked';
+    end if;
+
+    if v_form.edit_deadline < now() then
+        raise exception 'The deadline for editing responses has passed.' using hint = 'response-locked';
+    end if;
+
+    if p_response.submitted + make_interval(secs => v_form.edit_window) < now() then
+        raise exception 'The time allowed for editing this response has passed.' using hint = 'response-locked';
+    end if;
+end;
+$$ language plpgsql;
+
+-- respondents can only see the score of their response once the scores of
+-- the form have been released
+create or replace function hide_unreleased_score(
+    p_response responses,
+    p_user_id text
+) returns responses as $$
+begin
+    if p_response.score is not null and
+        not exists (select 1 from forms f where f.id = p_response.form and f.scores_released) and
+        not has_form_permission(p_user_id, p_response.form, 'analyze'::permission_role) then
+        p_response.score := null;
+    end if;
+
+    return p_response;
+end;
+$$ language plpgsql;
+
+create or replace function list_responses_for_form(
+    p_form_id text,
+    p_user_id text,
+    p_status response_status,
+    p_sort_by text,
+    p_order text,
+    p_limit int,
+    p_offset int
+) returns setof responses as $$
+begin
+    if has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        return query select * from responses r where r.form = p_form_id
+        and r.status = p_status order by
+            case when p_sort_by = 'submitted' and p_order = 'asc' then r.submitted end asc,
+            case when p_sort_by = 'submitted' and p_order = 'desc' then r.submitted end desc,
+            case when p_sort_by = 'started' and p_order = 'asc' then r.started end asc,
+            case when p_sort_by = 'started' and p_order = 'desc' then r.started end desc,
+            case when p_sort_by = 'edited' and p_order = 'asc' then r.edited end asc,
+            case when p_sort_by = 'edited' and p_order = 'desc' then r.edited end desc
+        limit p_limit offset p_offset;
+
+        return;
+    end if;
+
+    if has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
+        return query select (hide_unreleased_score(r, p_user_id)).* from responses r
+        where r.form = p_form_id and r.status = p_status and r.respondent = p_user_id order by
+            case when p_sort_by = 'submitted' and p_order = 'asc' then r.submitted end asc,
+            case when p_sort_by = 'submitted' and p_order = 'desc' then r.submitted end desc,
+            case when p_sort_by = 'started' and p_order = 'asc' then r.started end asc,
+            case when p_sort_by = 'started' and p_order = 'desc' then r.started end desc,
+            case when p_sort_by = 'edited' and p_order = 'asc' then r.edited end asc,
+            case when p_sort_by = 'edited' and p_order = 'desc' then r.edited end desc
+        limit p_limit offset p_offset;
+
+        return;
+    end if;
+
+    raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+end;
+$$ language plpgsql;
+
+create or replace function count_responses_for_form(
+    p_form_id text,
+    p_user_id text,
+    p_status response_status
+) returns bigint as $$
+declare
+    v_count bigint;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    select count(distinct r.id) into v_count from responses r
+    where r.form = p_form_id and r.status = p_status;
+
+    return v_count;
+end;
+$$ language plpgsql;
+
+create or replace function get_form_for_analysis(
+    p_form_id text,
+    p_user_id text
+) returns forms as $$
+declare
+    v_form forms;
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    select * into v_form from forms where id = p_form_id;
+
+    return v_form;
+end;
+$$ language plpgsql;
+
+create or replace function list_versions_for_analysis(
+    p_form_id text,
+    p_user_id text
+) returns setof form_versions as $$
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    return query select * from form_versions fv where fv.form = p_form_id
+    order by fv.version desc;
+end;
+$$ language plpgsql;
+
+-- responses are exported in batches, each starting after the last response of
+-- the previous one
+create or replace function export_responses_for_form(
+    p_form_id text,
+    p_user_id text,
+    p_status response_status,
+    p_after text,
+    p_limit int
+) returns setof response_exports as $$
+begin
+    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    return v_group;
+    return query select
+        r.id, r.version, r.status, r.started, r.submitted, r.edited, r.score,
+        case when f.anonymous is true then null else u.handle end,
+        case when f.anonymous is true then null else u.email end,
+        coalesce((
+            select jsonb_object_agg(a.question, a.value) from answers a
+            where a.response = r.id
+        ), '{}'::jsonb),
+        array(
+            select distinct h.question from answer_history h
+            where h.response = r.id order by h.question
+        )
+    from responses r
+    join forms f on f.id = r.form
+    left join users u on u.id = r.respondent
+    where r.form = p_form_id and r.status = p_status and (p_after is null or r.id > p_after)
+    order by r.id limit p_limit;
+end;
+$$ language plpgsql;
+
+-- answers to the given questions, from responses with the given status, that
+-- are not empty. used by the analytics functions, which check permissions.
+create or replace function answers_for_analysis(
+    p_form_id text,
+    p_status response_status,
+    p_questions text[]
+) returns setof answers as $$
+begin
+    return query select a.* from answers a
+    join responses r on r.id = a.response
+    where r.form = p_form_id and r.status = p_status and a.question = any(p_questions)
+    and a.value not in ('null'::jsonb, '""'::jsonb, '[]'::jsonb, '{}'::jsonb);
 end;
 $$ language plpgsql;
 
-create or replace function update_group_by_id(
-    p_id text,
+-- counts how often each value was picked. values in lists are counted
+-- separately, and objects are counted per key, which is reported as the
+-- category.
+create or replace function count_choices_for_form(
+    p_form_id text,
     p_user_id text,
-    p_name text,
-    p_description text
-) returns group_with_details as $$
-declare
-    v_group group_with_details;
+    p_status response_status,
+    p_questions text[]
+) returns setof choice_counts as $$
 begin
-    if not has_group_permission(p_user_id, p_id, null) then
-        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    update groups set
-        name = coalesce(p_name, name),
-        description = coalesce(p_description, description)
-    where id = p_id and owner = p_user_id
-    returning * into v_group;
+    return query
+    with given as (
+        select a.question, a.value from answers_for_analysis(p_form_id, p_status, p_questions) a
+    ), answered as (
+        select g.question, count(*) as answered from given g group by g.question
+    ), choices as (
+        select g.question, null::text as category, e.value from given g,
+        lateral jsonb_array_elements_text(g.value) e(value)
+        where jsonb_typeof(g.value) = 'array'
 
-    return v_group;
+        union all
+
+        select g.question, e.key, e.value from given g,
+        lateral jsonb_each_text(g.value) e
+        where jsonb_typeof(g.value) = 'object'
+
+        union all
+
+        select g.question, null::text, g.value #>> '{}' from given g
+        where jsonb_typeof(g.value) in ('string', 'number')
+    )
+    select c.question, c.category, c.value, count(*), an.answered
+    from choices c join answered an on an.question = c.question
+    where c.value is not null
+    group by c.question, c.category, c.value, an.answered;
 end;
 $$ language plpgsql;
 
-create or replace function update_domain_for_group(
-    p_id text,
+create or replace function summarize_numbers_for_form(
+    p_form_id text,
     p_user_id text,
-    p_domain text
-) returns void as $$
+    p_status response_status,
+    p_questions text[]
+) returns setof number_summaries as $$
 begin
-    if not has_group_permission(p_user_id, p_id, 'domain'::group_type) then
-        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    update group_domain_rules set domain = p_domain where "group" = p_id;
+    return query
+    with given as (
+        select a.question, (a.value #>> '{}')::double precision as number
+        from answers_for_analysis(p_form_id, p_status, p_questions) a
+        where jsonb_typeof(a.value) = 'number'
+    )
+    select g.question, count(*), avg(g.number),
+        percentile_cont(0.5) within group (order by g.number)
+    from given g group by g.question;
 end;
 $$ language plpgsql;
 
-create or replace function delete_group_by_id(
-    p_id text,
-    p_user_id text
-) returns void as $$
+-- summarizes text answers, along with up to p_samples of the latest answers
+create or replace function summarize_text_for_form(
+    p_form_id text,
+    p_user_id text,
+    p_status response_status,
+    p_questions text[],
+    p_samples int
+) returns setof text_summaries as $$
 begin
-    if not has_group_permission(p_user_id, p_id) then
-        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    delete from groups where id = p_id;
+    return query
+    with given as (
+        select a.question, a.value #>> '{}' as text, a.modified
+        from answers_for_analysis(p_form_id, p_status, p_questions) a
+        where jsonb_typeof(a.value) = 'string'
+    ), measured as (
+        select g.question, g.text, g.modified, char_length(g.text) as chars,
+            case when btrim(g.text) = '' then 0
+            else array_length(regexp_split_to_array(btrim(g.text), '\s+'), 1) end as words
+        from given g
+    )
+    select m.question, count(*),
+        avg(m.chars)::double precision, min(m.chars), max(m.chars),
+        avg(m.words)::double precision, min(m.words), max(m.words),
+        (array_agg(m.text order by m.modified desc))[1:p_samples]
+    from measured m group by m.question;
 end;
 $$ language plpgsql;
 
-create or replace function add_group_member_by_email(
-    p_group_id text,
+-- only the token is kept for anonymous forms and guests, to enforce the
+-- individual limit
+create or replace function participant_for_form(
+    p_form forms,
     p_user_id text,
-    p_target_user text
-) returns void as $$
-declare
-    v_target_user_id text;
+    p_token text
+) returns text as $$
 begin
-    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
-        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    if p_form.anonymous is true or p_user_id is null then
+        return p_token;
     end if;
 
-    select u.id into v_target_user_id from users u where u.email = p_target_user;
-    if not found then
-        raise exception 'User with email % does not exist.', p_target_user using hint = 'not-found';
-    end if;
+    return p_user_id;
+end;
+$$ language plpgsql;
 
-    insert into group_list_members ("group", "user")
-    values (p_group_id, v_target_user_id) on conflict do nothing;
+create or replace function count_submissions_by_participant(
+    p_form forms,
+    p_participant text
+) returns int as $$
+begin
+    return (
+        select coalesce(sum(sr.responses), 0) from submission_records sr
+        where sr.form = p_form.id and sr.participant = p_participant
+    );
 end;
 $$ language plpgsql;
 
-create or replace function remove_group_member_by_id(
-    p_group_id text,
-    p_user_id text,
-    p_target_user_id text
+-- responses only count towards the limits of a form once submitted, so that
+-- drafts do not take up places. to count concurrent submissions one at a time,
+-- callers that go on to submit must hold a lock on the row of the form.
+create or replace function check_response_limits(
+    p_form forms,
+    p_participant text
 ) returns void as $$
+declare
+    v_existing_count int;
+    v_total_count int;
 begin
-    if not has_group_permission(p_user_id, p_group_id, 'list'::group_type) then
-        raise exception 'Group not found or you do not have permission to do this.' using hint = 'forbidden';
+    select coalesce(sum(sr.responses), 0) into v_total_count from submission_records sr
+    where sr.form = p_form.id;
+    v_existing_count := count_submissions_by_participant(p_form, p_participant);
+
+    if p_form.max_responses is not null and p_form.max_responses <= v_total_count then
+        raise exception 'Form reached maximum responses.' using hint = 'form-closed';
     end if;
 
-    delete from group_list_members
-    where "group" = p_group_id and "user" = p_target_user_id;
+    if p_form.individual_limit <= v_existing_count then
+        raise exception 'Maximum responses submitted.' using hint = 'form-closed';
+    end if;
 end;
 $$ language plpgsql;
 
-create or replace function list_comments_for_form(
-    p_form_id text,
-    p_user_id text
-) returns setof comments as $$
+create or replace function check_form_open(
+    p_form forms
+) returns void as $$
 begin
-    if not has_form_permission(p_user_id, p_form_id, 'comment'::permission_role) then
-        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    if p_form.opens > now() then
+        raise exception 'Form not yet open.' using hint = 'form-closed';
     end if;
 
-    return query select * from comments where form = p_form_id
-    order by modified desc;
+    if p_form.closes < now() then
+        raise exception 'Form closed.' using hint = 'form-closed';
+    end if;
 end;
 $$ language plpgsql;
 
-create or replace function create_comment_on_form(
-    p_form_id text, p_user_id text,
-    p_body text, p_element text, p_parent text
-) returns comments as $$
+-- whether a user could start a response to a form now, making the same checks
+-- as start_response_for_form, and how many more responses they can submit
+create or replace function get_access_for_form(
+    p_form_id text,
+    p_user_id text,
+    p_token text
+) returns form_access as $$
 declare
-    v_comment comments;
+    v_form forms;
+    v_participant text;
+    v_access form_access;
 begin
-    if not has_form_permission(p_user_id, p_form_id, 'comment'::permission_role) then
+    if not has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
         raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    insert into comments (form, commenter, body, element, parent)
-    values (p_form_id, p_user_id, p_body, p_element, p_parent)
-    returning * into v_comment;
+    select * into v_form from forms f where f.id = p_form_id;
+    v_participant := participant_for_form(v_form, p_user_id, p_token);
+
+    v_access.open := true;
+    begin
+        perform check_form_open(v_form);
+        perform check_response_limits(v_form, v_participant);
+    exception when raise_exception then
+        v_access.open := false;
+        v_access.reason := sqlerrm;
+    end;
+
+    v_access.individual_limit := v_form.individual_limit;
+    v_access.remaining := greatest(
+        v_form.individual_limit - count_submissions_by_participant(v_form, v_participant), 0
+    );
 
-    return v_comment;
+    return v_access;
 end;
 $$ language plpgsql;
 
-create or replace function update_comment_by_id(
-    p_id text, p_form_id text, p_user_id text,
-    p_body text, p_state comment_state
-) returns comments as $$
+create or replace function start_response_for_form(
+    p_form_id text,
+    p_user_id text,
+    p_token text,
+    p_key text
+) returns responses as $$
 declare
-    v_comment comments;
+    v_form forms;
+    v_response responses;
 begin
-    if not has_form_permission(p_user_id, p_form_id, 'comment'::permission_role) then
+    if not has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
         raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    select * into v_comment from comments where id = p_id and form = p_form_id;
-    if not found then
-        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
-    end if;
+    select * into v_form from forms f where f.id = p_form_id;
 
-    if v_comment.commenter != p_user_id then
-        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    perform check_form_open(v_form);
+
+    -- only to fail early, the limits are enforced on submission
+    perform check_response_limits(v_form, participant_for_form(v_form, p_user_id, p_token));
+
+    if v_form.anonymous is true or p_user_id is null then
+        insert into responses (form, key_hash, version)
+        values (p_form_id, encode(digest(p_key, 'sha256'), 'hex'), v_form.version)
+        returning * into v_response;
+    else
+        insert into responses (form, respondent, version)
+        values (p_form_id, p_user_id, v_form.version)
+        returning * into v_response;
     end if;
 
-    update comments set
-        body = coalesce(p_body, body),
-        state = coalesce(p_state, state)
-    where id = p_id returning * into v_comment;
+    perform record_audit(
+        v_response.respondent, 'response.start', p_form_id, null, v_response.id,
+        null, to_jsonb(v_response) - 'key_hash'
+    );
 
-    return v_comment;
+    return v_response;
 end;
 $$ language plpgsql;
 
-create or replace function delete_comment_by_id(
-    p_id text, p_form_id text, p_user_id text
-) returns void as $$
+create or replace function get_response_by_id(
+    p_id text,
+    p_form_id text,
+    p_user_id text,
+    p_key text
+) returns responses as $$
 declare
-    v_comment comments;
+    v_response responses;
 begin
-    select * into v_comment from comments where id = p_id and form = p_form_id;
-
+    select * into v_response from responses where id = p_id and form = p_form_id;
     if not found then
-        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+        raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    if v_comment.commenter != p_user_id and not has_form_permission(p_user_id, p_form_id, 'manage'::permission_role) then
-        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    if not owns_response(v_response, p_user_id, p_key) and
+        not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    delete from comments where id = p_id;
+    return hide_unreleased_score(v_response, p_user_id);
 end;
 $$ language plpgsql;
 
-create or replace function list_responses_for_form(
+create or replace function get_answers_for_response(
+    p_id text,
     p_form_id text,
     p_user_id text,
-    p_status response_status,
-    p_sort_by text,
-    p_order text,
-    p_limit int,
-    p_offset int
-) returns setof responses as $$
+    p_key text
+) returns setof answers as $$
+declare
+    v_response responses;
 begin
-    if has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
-        return query select * from responses r where r.form = p_form_id
-        and r.status = p_status order by
-            case when p_sort_by = 'submitted' and p_order = 'asc' then r.submitted end asc,
-            case when p_sort_by = 'submitted' and p_order = 'desc' then r.submitted end desc,
-            case when p_sort_by = 'started' and p_order = 'asc' then r.started end asc,
-            case when p_sort_by = 'started' and p_order = 'desc' then r.started end desc,
-            case when p_sort_by = 'edited' and p_order = 'asc' then r.edited end asc,
-            case when p_sort_by = 'edited' and p_order = 'desc' then r.edited end desc
-        limit p_limit offset p_offset;
-
-        return;
+    select * into v_response from responses r
+    where r.id = p_id and r.form = p_form_id;
+    if not found then
+        raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    if has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
-        return query select * from responses r where r.form = p_form_id
-        and r.status = p_status and r.respondent = p_user_id order by
-            case when p_sort_by = 'submitted' and p_order = 'asc' then r.submitted end asc,
-            case when p_sort_by = 'submitted' and p_order = 'desc' then r.submitted end desc,
-            case when p_sort_by = 'started' and p_order = 'asc' then r.started end asc,
-            case when p_sort_by = 'started' and p_order = 'desc' then r.started end desc,
-            case when p_sort_by = 'edited' and p_order = 'asc' then r.edited end asc,
-            case when p_sort_by = 'edited' and p_order = 'desc' then r.edited end desc
-        limit p_limit offset p_offset;
-
-        return;
+    if not owns_response(v_response, p_user_id, p_key) and
+        not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+    return query select * from answers a where a.response = p_id; 
 end;
 $$ language plpgsql;
 
-create or replace function count_responses_for_form(
+create or replace function get_form_for_response(
+    p_id text,
     p_form_id text,
     p_user_id text,
-    p_status response_status
-) returns bigint as $$
+    p_key text
+) returns forms as $$
 declare
-    v_count bigint;
+    v_form forms;
+    v_response responses;
 begin
+    select * into v_response from responses r
+    where r.id = p_id and r.form = p_form_id;
+    if not found then
+        raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
+    end if;
+
+    -- analysts need the structure to make sense of the answers of a response
     if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
-        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
+        if not has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
+            raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
+        end if;
+
+        if not owns_response(v_response, p_user_id, p_key) then
+            raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
+        end if;
     end if;
 
-    select count(distinct r.id) into v_count from responses r
-    where r.form = p_form_id and r.status = p_status;
+    -- answers are always checked against the version the response started on
+    select * into v_form from forms where id = p_form_id;
+    select fv.structure into v_form.structure from form_versions fv
+    where fv.form = p_form_id and fv.version = v_response.version;
+    v_form.version := v_response.version;
 
-    return v_count;
+    return v_form;
 end;
 $$ language plpgsql;
 
-create or replace function start_response_for_form(
+create or replace function add_answer_to_response(
+    p_id text,
     p_form_id text,
-    p_user_id text
-) returns responses as $$
+    p_user_id text,
+    p_key text,
+    p_question text,
+    p_value text
+) returns answers as $$
 declare
-    v_form forms;
+    v_answer answers;
     v_response responses;
-    v_existing_count int;
-    v_total_count int;
+    v_old_value jsonb;
 begin
-    if not has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
-        raise exception 'Form not found or you do not have permission do this.' using hint = 'forbidden';
-    end if;
-
-    select * into v_form from forms f where f.id = p_form_id;
-
-    select sr.responses into v_existing_count from submission_records sr
-    where sr.form = p_form_id and sr."user" = p_user_id;
-    select sum(sr.responses) into v_total_count from submission_records sr
-    where sr.form = p_form_id and sr."user" = p_user_id;
-
-    if v_form.opens > now() then
-        raise exception 'Form not yet open.' using hint = 'form-closed';
+    select * into v_response from responses r
+    where r.id = p_id and r.form = p_form_id;
+    if not found then
+        raise exception 'Response not found or you do not have permission do this 1.' using hint = 'forbidden';
     end if;
 
-    if v_form.closes < now() then
-        raise exception 'Form closed.' using hint = 'form-closed';
+    if not has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
+        raise exception 'Response not found or you do not have permission do this 2.' using hint = 'forbidden';
     end if;
 
-    if v_form.max_responses is not null and v_form.max_responses <= v_total_count then
-        raise exception 'Form reached maximum responses.' using hint = 'form-closed';
+    if not owns_response(v_response, p_user_id, p_key) then
+        raise exception 'Response not found or you do not have permission do this 3.' using hint = 'forbidden';
     end if;
 
-    if v_existing_count is not null and v_form.individual_limit <= v_existing_count then
-        raise exception 'Maximum responses submitted.' using hint = 'form-closed';
-    end if;
+    perform check_response_editable(v_response);
 
-    insert into responses (form, respondent) values (p_form_id, p_user_id)
-    returning * into v_response;
+    select a.value into v_old_value from answers a
+    where a.response = p_id and a.question = p_question;
 
-    insert into submission_records (form, "user", responses)
-    values (p_form_id, p_user_id, 1) on conflict (form, "user") do update
-    set responses = submission_records.responses + excluded.responses;
+    insert into answers (response, question, value) values (
+        p_id, p_question, p_value::jsonb
+    ) on conflict (response, question) do update
+    set value = excluded.value, modified = now() returning * into v_answer;
+
+    -- changes after submission are kept, since they replace what was submitted.
+    -- the audit log only notes which answer changed, leaving the values to the
+    -- history, and leaves out anonymous forms. drafts, which are saved as they
+    -- are typed, are left out of both
+    if v_response.submitted is not null and v_old_value is distinct from v_answer.value then
+        insert into answer_history (response, question, old_value, new_value, author)
+        values (p_id, p_question, v_old_value, v_answer.value, v_response.respondent);
+
+        if not exists (select 1 from forms f where f.id = p_form_id and f.anonymous is true) then
+            perform record_audit(
+                v_response.respondent, 'response.answer.save', p_form_id, null, p_id,
+                null, jsonb_build_object('question', p_question)
+            );
+        end if;
+    end if;
 
-    return v_response;
+    return v_answer;
 end;
 $$ language plpgsql;
 
-create or replace function get_response_by_id(
+-- answers are saved in a transaction that starts by locking the response and
+-- advancing its revision. clients autosaving a draft pass the revision they
+-- last saw, and are turned away if it has changed since, so that an older
+-- save cannot overwrite a newer one.
+create or replace function advance_response_revision(
     p_id text,
     p_form_id text,
-    p_user_id text
-) returns responses as $$
+    p_user_id text,
+    p_key text,
+    p_revision int
+) returns int as $$
 declare
     v_response responses;
 begin
-    select * into v_response from responses where id = p_id;
+    select * into v_response from responses r
+    where r.id = p_id and r.form = p_form_id for update;
     if not found then
         raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+    if not has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
         raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
-    elsif v_response.respondent != p_user_id then
+    end if;
+
+    if not owns_response(v_response, p_user_id, p_key) then
         raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    return v_response;
+    perform check_response_editable(v_response);
+
+    if p_revision is not null and p_revision != v_response.revision then
+        raise exception 'Response was saved elsewhere since revision %.', p_revision using hint = 'stale-revision';
+    end if;
+
+    update responses set revision = revision + 1 where id = p_id
+    returning revision into v_response.revision;
+
+    return v_response.revision;
 end;
 $$ language plpgsql;
 
-create or replace function get_answers_for_response(
+create or replace function remove_answers_from_response(
     p_id text,
     p_form_id text,
-    p_user_id text
-) returns setof answers as $$
+    p_user_id text,
+    p_key text,
+    p_questions text[]
+) returns void as $$
 declare
-    v_respondent text;
+    v_response responses;
 begin
-    select respondent into v_respondent from responses r where r.id = p_id;
+    select * into v_response from responses r
+    where r.id = p_id and r.form = p_form_id;
     if not found then
         raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+    if not has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
         raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
-    elsif v_respondent != p_user_id then
+    end if;
+
+    if not owns_response(v_response, p_user_id, p_key) then
         raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    return query select * from answers a where a.response = p_id; 
+    perform check_response_editable(v_response);
+
+    if v_response.submitted is not null then
+        insert into answer_history (response, question, old_value, new_value, author)
+        select p_id, a.question, a.value, null, v_response.respondent from answers a
+        where a.response = p_id and a.question = any(p_questions);
+
+        if not exists (select 1 from forms f where f.id = p_form_id and f.anonymous is true) then
+            perform record_audit(
+                v_response.respondent, 'response.answer.remove', p_form_id, null, p_id,
+                jsonb_build_object('question', a.question), null
+            ) from answers a where a.response = p_id and a.question = any(p_questions);
+        end if;
+    end if;
+
+    delete from answers a where a.response = p_id and a.question = any(p_questions);
 end;
 $$ language plpgsql;
 
-create or replace function add_answer_to_response(
+create or replace function list_history_for_response(
     p_id text,
     p_form_id text,
-    p_user_id text,
-    p_question text,
-    p_value text
-) returns answers as $$
-declare
-    v_answer answers;
-    v_respondent text;
+    p_user_id text
+) returns setof answer_history as $$
 begin
-    select respondent into v_respondent from responses r where r.id = p_id;
-    if not found then
-        raise exception 'Response not found or you do not have permission do this 1.' using hint = 'forbidden';
-    end if;
-
-    if not has_form_permission(p_user_id, p_form_id, 'respond'::permission_role) then
-        raise exception 'Response not found or you do not have permission do this 2.' using hint = 'forbidden';
+    if not has_form_permission(p_user_id, p_form_id, 'analyze'::permission_role) then
+        raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    if v_respondent is not null and v_respondent != p_user_id then
-        raise exception 'Response not found or you do not have permission do this 3.' using hint = 'forbidden';
+    if not exists (select 1 from responses r where r.id = p_id and r.form = p_form_id) then
+        raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    insert into answers (response, question, value) values (
-        p_id, p_question, p_value::jsonb
-    ) on conflict (response, question) do update
-    set value = excluded.value returning * into v_answer;
-
-    return v_answer;
+    return query select * from answer_history h where h.response = p_id
+    order by h.changed, h.id;
 end;
 $$ language plpgsql;
 
@@ -751,12 +2289,19 @@ create or replace function submit_response_by_id(
     p_id text,
     p_form_id text,
     p_user_id text,
-    p_save boolean
+    p_key text,
+    p_token text,
+    p_save boolean,
+    p_score int
 ) returns responses as $$
 declare
+    v_form forms;
     v_response responses;
+    v_participant text;
+    v_before jsonb;
 begin
-    select * into v_response from responses r where r.id = p_id;
+    -- locked so that submitting the same response twice at once only counts once
+    select * into v_response from responses r where r.id = p_id and r.form = p_form_id for update;
     if not found then
         raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
@@ -765,25 +2310,45 @@ begin
         raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
-    if v_response.respondent is not null and v_response.respondent != p_user_id then
+    if not owns_response(v_response, p_user_id, p_key) then
         raise exception 'Response not found or you do not have permission do this.' using hint = 'forbidden';
     end if;
 
+    perform check_response_editable(v_response);
+    v_before := to_jsonb(v_response) - 'key_hash';
+
     if v_response.submitted is not null then
-        update responses set status = 'edited', edited = now()
+        update responses set status = 'edited', edited = now(), score = p_score
         where responses.id = p_id returning * into v_response;
     else
-        update responses set status = 'completed', submitted = now()
+        -- held until the end of the transaction, so that submissions to the
+        -- form are counted one at a time
+        select * into v_form from forms f where f.id = p_form_id for update;
+
+        v_participant := participant_for_form(v_form, p_user_id, p_token);
+        perform check_response_limits(v_form, v_participant);
+
+        update responses set status = 'completed', submitted = now(), score = p_score
         where responses.id = p_id returning * into v_response;
+
+        insert into submission_records (form, participant, responses)
+        values (p_form_id, v_participant, 1) on conflict (form, participant) do update
+        set responses = submission_records.responses + excluded.responses;
     end if;
 
-    if p_save then
+    -- saving a response to an anonymous form would link it to the respondent
+    if p_save and v_response.respondent is not null then
         insert into saved_responses ("user", form, response) values (
             p_user_id, p_form_id, p_id
         ) on conflict do nothing;
     end if;
 
-    return v_response;
+    perform record_audit(
+        v_response.respondent, 'response.submit', p_form_id, null, p_id,
+        v_before, to_jsonb(v_response) - 'key_hash'
+    );
+
+    return hide_unreleased_score(v_response, p_user_id);
 end;
 $$ language plpgsql;
 
@@ -797,7 +2362,7 @@ create or replace function list_responses_for_user(
     p_offset int
 ) returns setof responses as $$
 begin
-    return query select r.* from saved_responses sr
+    return query select (hide_unreleased_score(r, p_user_id)).* from saved_responses sr
     join responses r on r.id = sr.response join forms f on f.id = sr.form
     where sr."user" = p_user_id and (p_status is null or r.status = p_status)
     and (p_form_title = '' or f.title %> p_form_title) order by
@@ -828,3 +2393,361 @@ begin
     return v_count;
 end;
 $$ language plpgsql;
+
+-- creates a user on their first login, giving them everything they were
+-- invited to before then
+create or replace function ensure_user(
+    p_handle text,
+    p_email text,
+    p_name text,
+    p_admin boolean
+) returns users as $$
+declare
+    v_user users;
+begin
+    insert into users (handle, email, name, admin)
+    values (p_handle, p_email, p_name, p_admin)
+    on conflict do nothing returning * into v_user;
+
+    if not found then
+        update users set admin = p_admin where email = p_email
+        returning * into v_user;
+        return v_user;
+    end if;
+
+    insert into form_permissions (form, role, "user", starts, expires)
+    select pp.form, pp.role, v_user.id, pp.starts, pp.expires from pending_permissions pp
+    where pp.email = p_email on conflict do nothing;
+    delete from pending_permissions where email = p_email;
+
+    insert into group_list_members ("group", "user")
+    select pgm."group", v_user.id from pending_group_members pgm
+    where pgm.email = p_email on conflict do nothing;
+    delete from pending_group_members where email = p_email;
+
+    return v_user;
+end;
+$$ language plpgsql;
+
+-- site administration. every function here checks that the caller is an
+-- administrator, and records what was changed in the audit log
+
+create or replace function list_all_forms(
+    p_user_id text,
+    p_search text,
+    p_limit int,
+    p_offset int
+) returns setof forms as $$
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    return query select f.* from forms f
+    where p_search = '' or f.title %> p_search or f.slug ilike '%' || p_search || '%'
+    order by f.id desc
+    limit p_limit offset p_offset;
+end;
+$$ language plpgsql;
+
+create or replace function count_all_forms(
+    p_user_id text,
+    p_search text
+) returns bigint as $$
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    return (
+        select count(*) from forms f
+        where p_search = '' or f.title %> p_search or f.slug ilike '%' || p_search || '%'
+    );
+end;
+$$ language plpgsql;
+
+create or replace function list_all_users(
+    p_user_id text,
+    p_search text,
+    p_limit int,
+    p_offset int
+) returns setof users as $$
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    return query select u.* from users u
+    where p_search = '' or u.name ilike '%' || p_search || '%' or
+        u.email ilike '%' || p_search || '%' or u.handle ilike '%' || p_search || '%'
+    order by u.id desc
+    limit p_limit offset p_offset;
+end;
+$$ language plpgsql;
+
+create or replace function count_all_users(
+    p_user_id text,
+    p_search text
+) returns bigint as $$
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    return (
+        select count(*) from users u
+        where p_search = '' or u.name ilike '%' || p_search || '%' or
+            u.email ilike '%' || p_search || '%' or u.handle ilike '%' || p_search || '%'
+    );
+end;
+$$ language plpgsql;
+
+create or replace function list_all_groups(
+    p_user_id text,
+    p_search text,
+    p_limit int,
+    p_offset int
+) returns setof groups as $$
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    return query select g.* from groups g
+    where p_search = '' or g.name ilike '%' || p_search || '%'
+    order by g.id desc
+    limit p_limit offset p_offset;
+end;
+$$ language plpgsql;
+
+create or replace function count_all_groups(
+    p_user_id text,
+    p_search text
+) returns bigint as $$
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    return (
+        select count(*) from groups g
+        where p_search = '' or g.name ilike '%' || p_search || '%'
+    );
+end;
+$$ language plpgsql;
+
+create or replace function reassign_form(
+    p_form_id text,
+    p_user_id text,
+    p_target_user text
+) returns forms as $$
+declare
+    v_target_user_id text;
+    v_before forms;
+    v_form forms;
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    select * into v_before from forms where id = p_form_id;
+    if not found then
+        raise exception 'Form not found.' using hint = 'not-found';
+    end if;
+
+    select u.id into v_target_user_id from users u where u.email = p_target_user;
+    if not found then
+        raise exception 'User with email % does not exist.', p_target_user using hint = 'not-found';
+    end if;
+
+    v_form := move_form(p_form_id, v_target_user_id);
+
+    perform record_audit(
+        p_user_id, 'admin.form.reassign', p_form_id, null, p_form_id,
+        to_jsonb(v_before) - 'structure', to_jsonb(v_form) - 'structure'
+    );
+
+    return v_form;
+end;
+$$ language plpgsql;
+
+create or replace function reassign_group(
+    p_group_id text,
+    p_user_id text,
+    p_target_user text
+) returns groups as $$
+declare
+    v_target_user_id text;
+    v_before groups;
+    v_group groups;
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    select * into v_before from groups where id = p_group_id;
+    if not found then
+        raise exception 'Group not found.' using hint = 'not-found';
+    end if;
+
+    select u.id into v_target_user_id from users u where u.email = p_target_user;
+    if not found then
+        raise exception 'User with email % does not exist.', p_target_user using hint = 'not-found';
+    end if;
+
+    v_group := move_group(p_group_id, v_target_user_id);
+
+    perform record_audit(
+        p_user_id, 'admin.group.reassign', null, p_group_id, p_group_id,
+        to_jsonb(v_before), to_jsonb(v_group)
+    );
+
+    return v_group;
+end;
+$$ language plpgsql;
+
+create or replace function remove_form(
+    p_form_id text,
+    p_user_id text
+) returns void as $$
+declare
+    v_before jsonb;
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    with deleted as (
+        delete from forms where id = p_form_id returning *
+    ) select to_jsonb(d) - 'structure' into v_before from deleted d;
+
+    if v_before is null then
+        raise exception 'Form not found.' using hint = 'not-found';
+    end if;
+
+    perform record_audit(
+        p_user_id, 'admin.form.delete', p_form_id, null, p_form_id, v_before, null
+    );
+end;
+$$ language plpgsql;
+
+-- disabled users cannot log in, and their sessions stop working. their forms
+-- and groups are kept, and can be reassigned
+create or replace function set_user_disabled(
+    p_target_user_id text,
+    p_user_id text,
+    p_disabled boolean
+) returns users as $$
+declare
+    v_before users;
+    v_user users;
+begin
+    if not is_admin(p_user_id) then
+        raise exception 'Only administrators can do this.' using hint = 'forbidden';
+    end if;
+
+    if p_target_user_id = p_user_id then
+        raise exception 'You cannot disable yourself.' using hint = 'forbidden';
+    end if;
+
+    select * into v_before from users where id = p_target_user_id for update;
+    if not found then
+        raise exception 'User not found.' using hint = 'not-found';
+    end if;
+
+    update users set disabled = p_disabled where id = p_target_user_id
+    returning * into v_user;
+
+    if p_disabled then
+        delete from sessions where "user" = p_target_user_id;
+    end if;
+
+    perform record_audit(
+        p_user_id,
+        case when p_disabled then 'admin.user.disable' else 'admin.user.enable' end,
+        null, null, p_target_user_id,
+        jsonb_build_object('disabled', v_before.disabled),
+        jsonb_build_object('disabled', v_user.disabled)
+    );
+
+    return v_user;
+end;
+$$ language plpgsql;
+
+-- expired sessions of the user are cleaned up whenever they log in again
+create or replace function create_session(
+    p_user_id text,
+    p_expires timestamptz,
+    p_ip text,
+    p_user_agent text
+) returns sessions as $$
+declare
+    v_session sessions;
+begin
+    delete from sessions where "user" = p_user_id and expires <= now();
+
+    insert into sessions ("user", expires, ip, user_agent)
+    values (p_user_id, p_expires, p_ip, p_user_agent)
+    returning * into v_session;
+
+    return v_session;
+end;
+$$ language plpgsql;
+
+-- last_seen is only written once a minute, so every request is not a write
+create or replace function touch_session(
+    p_id text,
+    p_user_id text,
+    p_ip text,
+    p_user_agent text
+) returns sessions as $$
+declare
+    v_session sessions;
+begin
+    select * into v_session from sessions s
+    where s.id = p_id and s."user" = p_user_id and s.expires > now();
+
+    if not found then
+        raise exception 'Session not found or it has been revoked.' using hint = 'not-found';
+    end if;
+
+    if v_session.last_seen < now() - interval '1 minute' then
+        update sessions set last_seen = now(), ip = p_ip, user_agent = p_user_agent
+        where id = p_id
+        returning * into v_session;
+    end if;
+
+    return v_session;
+end;
+$$ language plpgsql;
+
+create or replace function list_sessions_for_user(
+    p_user_id text
+) returns setof sessions as $$
+begin
+    return query select * from sessions s
+    where s."user" = p_user_id and s.expires > now()
+    order by s.last_seen desc;
+end;
+$$ language plpgsql;
+
+create or replace function revoke_session(
+    p_id text,
+    p_user_id text
+) returns void as $$
+begin
+    delete from sessions where id = p_id and "user" = p_user_id;
+
+    if not found then
+        raise exception 'Session not found.' using hint = 'not-found';
+    end if;
+end;
+$$ language plpgsql;
+
+create or replace function revoke_sessions_for_user(
+    p_user_id text
+) returns void as $$
+begin
+    delete from sessions where "user" = p_user_id;
+end;
+$$ language plpgsql;
@@ -0,0 +1,71 @@
+package admin
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/labstack/echo/v4"
+)
+
+func ListAudit(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	type Query struct {
+		Actor  *string `query:"actor"`
+		Action *string `query:"action"`
+		Form   *string `query:"form"`
+		Group  *string `query:"group"`
+		Limit  int32   `query:"limit" validate:"gte=1,lte=100"`
+		Offset int32   `query:"offset" validate:"gte=0"`
+	}
+
+	query := Query{Limit: 20}
+
+	if err := c.Bind(&query); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	entries, err := cc.Query.ListAuditLog(
+		*cc.DbCtx,
+		db.ListAuditLogParams{
+			UserID:    user.ID,
+			Actor:     query.Actor,
+			Action:    query.Action,
+			Form:      query.Form,
+			GroupID:   query.Group,
+			LimitVal:  query.Limit,
+			OffsetVal: query.Offset,
+		},
+	)
+	if err != nil {
+		log.Error("failed to fetch audit log", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve audit log.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, utils.EmptyArrayIfNull(entries))
+}
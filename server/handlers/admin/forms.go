@@ -0,0 +1,167 @@
+package admin
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func ListForms(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	query, ok, err := bindListQuery(c)
+	if !ok {
+		return err
+	}
+
+	forms, err := cc.Query.ListAllForms(
+		*cc.DbCtx,
+		db.ListAllFormsParams{
+			UserID:    user.ID,
+			Search:    query.Search,
+			LimitVal:  query.Limit,
+			OffsetVal: query.Offset,
+		},
+	)
+	if err != nil {
+		log.Error("failed to fetch all forms", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to fetch forms.")),
+		)
+	}
+
+	total, err := cc.Query.CountAllForms(
+		*cc.DbCtx,
+		db.CountAllFormsParams{
+			UserID: user.ID,
+			Search: query.Search,
+		},
+	)
+	if err != nil {
+		log.Error("failed to count all forms", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to count forms.")),
+		)
+	}
+
+	return paginated(c, query, forms, total)
+}
+
+func ReassignForm(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Payload struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	payload := Payload{}
+
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	form, err := cc.Query.ReassignForm(
+		*cc.DbCtx,
+		db.ReassignFormParams{
+			FormID:     formID,
+			UserID:     user.ID,
+			TargetUser: payload.Email,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Hint {
+			case "forbidden":
+				return c.JSON(
+					http.StatusForbidden,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			case "not-found":
+				return c.JSON(
+					http.StatusNotFound,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			}
+		}
+
+		log.Error("failed to reassign form", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to reassign form.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, form)
+}
+
+func DeleteForm(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	err := cc.Query.RemoveForm(
+		*cc.DbCtx,
+		db.RemoveFormParams{
+			FormID: formID,
+			UserID: user.ID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Hint {
+			case "forbidden":
+				return c.JSON(
+					http.StatusForbidden,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			case "not-found":
+				return c.JSON(
+					http.StatusNotFound,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			}
+		}
+
+		log.Error("failed to remove form", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to delete form.")),
+		)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
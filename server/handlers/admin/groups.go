@@ -0,0 +1,137 @@
+package admin
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func ListGroups(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	query, ok, err := bindListQuery(c)
+	if !ok {
+		return err
+	}
+
+	groups, err := cc.Query.ListAllGroups(
+		*cc.DbCtx,
+		db.ListAllGroupsParams{
+			UserID:    user.ID,
+			Search:    query.Search,
+			LimitVal:  query.Limit,
+			OffsetVal: query.Offset,
+		},
+	)
+	if err != nil {
+		log.Error("failed to fetch all groups", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to fetch groups.")),
+		)
+	}
+
+	total, err := cc.Query.CountAllGroups(
+		*cc.DbCtx,
+		db.CountAllGroupsParams{
+			UserID: user.ID,
+			Search: query.Search,
+		},
+	)
+	if err != nil {
+		log.Error("failed to count all groups", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to count groups.")),
+		)
+	}
+
+	return paginated(c, query, groups, total)
+}
+
+func ReassignGroup(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+
+	type Payload struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	payload := Payload{}
+
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	group, err := cc.Query.ReassignGroup(
+		*cc.DbCtx,
+		db.ReassignGroupParams{
+			GroupID:    groupID,
+			UserID:     user.ID,
+			TargetUser: payload.Email,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return c.JSON(
+				http.StatusConflict,
+				utils.FromError(
+					utils.ErrorConflict,
+					errors.New("The new owner already has a group with the same name."),
+				),
+			)
+		}
+
+		if errors.As(err, &pgErr) {
+			switch pgErr.Hint {
+			case "forbidden":
+				return c.JSON(
+					http.StatusForbidden,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			case "not-found":
+				return c.JSON(
+					http.StatusNotFound,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			}
+		}
+
+		log.Error("failed to reassign group", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to reassign group.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, group)
+}
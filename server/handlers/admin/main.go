@@ -0,0 +1,57 @@
+package admin
+
+import (
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// listQuery is shared by every admin listing, which all search, page and sort
+// newest first the same way.
+type listQuery struct {
+	Search string `query:"q"`
+	Limit  int32  `query:"limit" validate:"gte=1,lte=100"`
+	Offset int32  `query:"offset" validate:"gte=0"`
+}
+
+// bindListQuery parses the query string of a listing, sending an error
+// response if it is invalid.
+func bindListQuery(c echo.Context) (listQuery, bool, error) {
+	query := listQuery{Limit: 20}
+
+	if err := c.Bind(&query); err != nil {
+		return query, false, c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return query, false, c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	return query, true, nil
+}
+
+func paginated[T any](c echo.Context, query listQuery, data []T, total int64) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": utils.EmptyArrayIfNull(data),
+		"pagination": map[string]int64{
+			"offset": int64(query.Offset),
+			"limit":  int64(query.Limit),
+			"total":  total,
+		},
+	})
+}
@@ -0,0 +1,126 @@
+package admin
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func ListUsers(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	query, ok, err := bindListQuery(c)
+	if !ok {
+		return err
+	}
+
+	users, err := cc.Query.ListAllUsers(
+		*cc.DbCtx,
+		db.ListAllUsersParams{
+			UserID:    user.ID,
+			Search:    query.Search,
+			LimitVal:  query.Limit,
+			OffsetVal: query.Offset,
+		},
+	)
+	if err != nil {
+		log.Error("failed to fetch all users", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to fetch users.")),
+		)
+	}
+
+	total, err := cc.Query.CountAllUsers(
+		*cc.DbCtx,
+		db.CountAllUsersParams{
+			UserID: user.ID,
+			Search: query.Search,
+		},
+	)
+	if err != nil {
+		log.Error("failed to count all users", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to count users.")),
+		)
+	}
+
+	return paginated(c, query, users, total)
+}
+
+func SetUserDisabled(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	targetUserID := c.Param("userId")
+
+	type Payload struct {
+		Disabled *bool `json:"disabled" validate:"required"`
+	}
+
+	var payload Payload
+
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	target, err := cc.Query.SetUserDisabled(
+		*cc.DbCtx,
+		db.SetUserDisabledParams{
+			TargetUserID: targetUserID,
+			UserID:       user.ID,
+			Disabled:     *payload.Disabled,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Hint {
+			case "forbidden":
+				return c.JSON(
+					http.StatusForbidden,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			case "not-found":
+				return c.JSON(
+					http.StatusNotFound,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			}
+		}
+
+		log.Error("failed to update disabled user", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to update user.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, target)
+}
@@ -6,11 +6,13 @@ import (
 	"backend/utility"
 	"encoding/json"
 	"io"
+	"time"
 
 	"net/http"
 	"net/url"
 
 	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 )
 
@@ -106,6 +108,7 @@ func Callback(c echo.Context) error {
 	cc := c.(*dbcontext.Context)
 	user, err := cc.Query.EnsureUser(*cc.DbCtx, db.EnsureUserParams{
 		Handle: attributes.ID[0], Email: attributes.Email[0], Name: attributes.Name[0],
+		Admin: utils.IsAdmin(attributes.ID[0]),
 	})
 
 	if err != nil {
@@ -117,13 +120,57 @@ func Callback(c echo.Context) error {
 		return c.Redirect(http.StatusFound, frontend.String())
 	}
 
-	session := utils.CreateSession(user.ID, utils.DefaultSessionTtl)
+	if user.Disabled {
+		query.Set("error_code", string(utils.ErrorForbidden))
+		query.Set("error_message", "Your account has been disabled.")
+		frontend.RawQuery = query.Encode()
+		return c.Redirect(http.StatusFound, frontend.String())
+	}
+
+	ip := c.RealIP()
+	userAgent := c.Request().UserAgent()
+	expires := time.Now().Add(utils.DefaultSessionTtl)
+
+	stored, err := cc.Query.CreateSession(*cc.DbCtx, db.CreateSessionParams{
+		UserID:    user.ID,
+		Expires:   pgtype.Timestamptz{Time: expires, Valid: true},
+		Ip:        &ip,
+		UserAgent: &userAgent,
+	})
+
+	if err != nil {
+		log.Error("failed to create session", "error", err.Error())
+
+		query.Set("error_code", string(utils.ErrorInternal))
+		query.Set("error_message", "An error occurred while logging you in. Please try again.")
+		frontend.RawQuery = query.Encode()
+		return c.Redirect(http.StatusFound, frontend.String())
+	}
+
+	session := utils.CreateSession(user.ID, stored.ID, expires)
 	c.SetCookie(session.Cookie())
 
 	return c.Redirect(http.StatusFound, frontend.String())
 }
 
+// Logout revokes the current session, if there is one, before logging out of
+// CAS as well.
 func Logout(c echo.Context) error {
+	cookie, err := c.Cookie(utils.SessionCookieName)
+	if err == nil {
+		session, err := utils.ValidateSession(cookie.Value)
+		if err == nil {
+			cc := c.(*dbcontext.Context)
+			err = cc.Query.RevokeSession(*cc.DbCtx, db.RevokeSessionParams{
+				ID: session.Session, UserID: session.ID,
+			})
+			if err != nil {
+				log.Warn("failed to revoke session on logout", "error", err.Error())
+			}
+		}
+	}
+
+	c.SetCookie(utils.DeletionCookie())
 	return c.Redirect(http.StatusFound, getLogoutUrl())
 }
 
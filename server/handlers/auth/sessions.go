@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+type sessionInfo struct {
+	db.Session
+	Current bool `json:"current"`
+}
+
+func ListSessions(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+	current := c.Get("session").(string)
+
+	sessions, err := cc.Query.ListSessions(*cc.DbCtx, user.ID)
+	if err != nil {
+		log.Error("failed to fetch sessions", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve sessions.")),
+		)
+	}
+
+	infos := make([]sessionInfo, len(sessions))
+	for i, session := range sessions {
+		infos[i] = sessionInfo{Session: session, Current: session.ID == current}
+	}
+
+	return c.JSON(http.StatusOK, infos)
+}
+
+// RevokeSession logs out of one session. Revoking the current session also
+// clears its cookie.
+func RevokeSession(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+	current := c.Get("session").(string)
+
+	sessionID := c.Param("sessionId")
+
+	err := cc.Query.RevokeSession(
+		*cc.DbCtx,
+		db.RevokeSessionParams{
+			ID:     sessionID,
+			UserID: user.ID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "not-found" {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to revoke session", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to revoke session.")),
+		)
+	}
+
+	if sessionID == current {
+		c.SetCookie(utils.DeletionCookie())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RevokeAllSessions logs out everywhere, including the current session.
+func RevokeAllSessions(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	if err := cc.Query.RevokeAllSessions(*cc.DbCtx, user.ID); err != nil {
+		log.Error("failed to revoke sessions", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to revoke sessions.")),
+		)
+	}
+
+	c.SetCookie(utils.DeletionCookie())
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,98 @@
+package forms
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func ListAudit(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Query struct {
+		Limit  int32 `query:"limit" validate:"gte=1,lte=100"`
+		Offset int32 `query:"offset" validate:"gte=0"`
+	}
+
+	query := Query{Limit: 20}
+
+	if err := c.Bind(&query); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(query); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	entries, err := cc.Query.ListFormAudit(
+		*cc.DbCtx,
+		db.ListFormAuditParams{
+			FormID:    formID,
+			UserID:    user.ID,
+			LimitVal:  query.Limit,
+			OffsetVal: query.Offset,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to fetch audit log", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve audit log.")),
+		)
+	}
+
+	total, err := cc.Query.CountFormAudit(
+		*cc.DbCtx,
+		db.CountFormAuditParams{
+			FormID: formID,
+			UserID: user.ID,
+		},
+	)
+	if err != nil {
+		log.Error("failed to count audit log", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to count audit log.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": utils.EmptyArrayIfNull(entries),
+		"pagination": map[string]int64{
+			"offset": int64(query.Offset),
+			"limit":  int64(query.Limit),
+			"total":  total,
+		},
+	})
+}
@@ -3,9 +3,12 @@ package forms
 import (
 	"backend/context"
 	"backend/db"
+	"backend/spec"
 	"backend/utility"
 	"errors"
 	"net/http"
+	"net/url"
+	"path"
 
 	"github.com/charmbracelet/log"
 	"github.com/jackc/pgerrcode"
@@ -84,6 +87,18 @@ func ListForms(c echo.Context) error {
 		)
 	}
 
+	// forms are listed without their structure, which would otherwise need its
+	// answer key removed one form at a time
+	type listedForm struct {
+		db.Form
+		Structure *string `json:"structure,omitempty"`
+	}
+
+	listed := make([]listedForm, len(forms))
+	for i, form := range forms {
+		listed[i] = listedForm{Form: form}
+	}
+
 	total, err := cc.Query.CountForms(
 		*cc.DbCtx,
 		db.CountFormsParams{
@@ -102,7 +117,7 @@ func ListForms(c echo.Context) error {
 	}
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"data": utils.EmptyArrayIfNull(forms),
+		"data": listed,
 		"pagination": map[string]int64{
 			"offset": int64(query.Offset),
 			"limit":  int64(query.Limit),
@@ -127,6 +142,9 @@ func CreateForm(c echo.Context) error {
 		MaxResponses      *int32              `json:"max_responses"`
 		IndividualLimit   int32               `json:"individual_limit" validate:"gte=1"`
 		EditableResponses bool                `json:"editable_responses"`
+		EditDeadline      *pgtype.Timestamptz `json:"edit_deadline"`
+		EditWindow        *int32              `json:"edit_window" validate:"omitempty,gte=1"`
+		Quiz              bool                `json:"quiz"`
 	}
 
 	payload := Payload{
@@ -157,6 +175,10 @@ func CreateForm(c echo.Context) error {
 		)
 	}
 
+	if _, err := spec.Parse(payload.Structure); err != nil {
+		return invalidStructure(c, err, "Failed to process payload - structure is invalid.")
+	}
+
 	form, err := cc.Query.CreateFormWithPermissions(
 		*cc.DbCtx,
 		db.CreateFormWithPermissionsParams{
@@ -172,12 +194,22 @@ func CreateForm(c echo.Context) error {
 			MaxResponses:      payload.MaxResponses,
 			IndividualLimit:   payload.IndividualLimit,
 			EditableResponses: payload.EditableResponses,
+			EditDeadline:      payload.EditDeadline,
+			EditWindow:        payload.EditWindow,
+			Quiz:              payload.Quiz,
 		},
 	)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
+			if pgErr.Hint == "reserved-slug" {
+				return c.JSON(
+					http.StatusUnprocessableEntity,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			}
+
 			if pgErr.Code == pgerrcode.CheckViolation {
 				return c.JSON(
 					http.StatusUnprocessableEntity,
@@ -211,7 +243,12 @@ func CreateForm(c echo.Context) error {
 
 func ResolveForm(c echo.Context) error {
 	cc := c.(*dbcontext.Context)
-	user := c.Get("user").(db.User)
+
+	// guests can open forms shared with anyone who has the link
+	var userID *string
+	if user, ok := c.Get("user").(db.User); ok {
+		userID = &user.ID
+	}
 
 	handle := c.Param("handle")
 	slug := c.Param("slug")
@@ -221,13 +258,31 @@ func ResolveForm(c echo.Context) error {
 		db.ResolveFormByHandleAndSlugParams{
 			Handle: handle,
 			Slug:   slug,
-			UserID: user.ID,
+			UserID: userID,
 		},
 	)
 
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			// forms that changed owners can still be found by their earlier url, for as
+			// long as no other form takes it, so the redirect is not permanent
+			location, err := cc.Query.FindMovedForm(
+				*cc.DbCtx,
+				db.FindMovedFormParams{
+					Handle: handle,
+					Slug:   slug,
+					UserID: userID,
+				},
+			)
+			if err == nil {
+				base := path.Dir(path.Dir(c.Request().URL.Path))
+				return c.Redirect(
+					http.StatusFound,
+					path.Join(base, url.PathEscape(location.Handle), url.PathEscape(location.Slug)),
+				)
+			}
+
 			return c.JSON(
 				http.StatusForbidden,
 				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
@@ -241,6 +296,14 @@ func ResolveForm(c echo.Context) error {
 		)
 	}
 
+	if err := redactAnswerKey(cc, userID, &form); err != nil {
+		log.Error("failed to redact answer key", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve form.")),
+		)
+	}
+
 	return c.JSON(http.StatusOK, form)
 }
 
@@ -250,11 +313,41 @@ func GetForm(c echo.Context) error {
 
 	formID := c.Param("formId")
 
+	type Query struct {
+		Version *int32 `query:"version" validate:"omitempty,gte=1"`
+	}
+
+	var query Query
+
+	err := c.Bind(&query)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	err = utils.Validate.Struct(query)
+	if err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
 	form, err := cc.Query.GetFormByID(
 		*cc.DbCtx,
 		db.GetFormByIDParams{
-			ID:     formID,
-			UserID: user.ID,
+			ID:      formID,
+			UserID:  user.ID,
+			Version: query.Version,
 		},
 	)
 
@@ -267,6 +360,13 @@ func GetForm(c echo.Context) error {
 			)
 		}
 
+		if errors.As(err, &pgErr) && pgErr.Hint == "not-found" {
+			return c.JSON(
+				http.StatusNotFound,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
 		log.Error("failed to fetch form", "error", err)
 		return c.JSON(
 			http.StatusInternalServerError,
@@ -274,6 +374,14 @@ func GetForm(c echo.Context) error {
 		)
 	}
 
+	if err := redactAnswerKey(cc, &user.ID, &form); err != nil {
+		log.Error("failed to redact answer key", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve form.")),
+		)
+	}
+
 	return c.JSON(http.StatusOK, form)
 }
 
@@ -295,6 +403,9 @@ func UpdateForm(c echo.Context) error {
 		MaxResponses      *int32              `json:"max_responses"`
 		IndividualLimit   *int32              `json:"individual_limit" validate:"omitempty,gte=1"`
 		EditableResponses *bool               `json:"editable_responses"`
+		EditDeadline      *pgtype.Timestamptz `json:"edit_deadline"`
+		EditWindow        *int32              `json:"edit_window" validate:"omitempty,gte=1"`
+		Quiz              *bool               `json:"quiz"`
 	}
 
 	var payload Payload
@@ -320,6 +431,12 @@ func UpdateForm(c echo.Context) error {
 		)
 	}
 
+	if payload.Structure != nil {
+		if _, err := spec.Parse(*payload.Structure); err != nil {
+			return invalidStructure(c, err, "Failed to process payload - structure is invalid.")
+		}
+	}
+
 	form, err := cc.Query.UpdateFormByID(
 		*cc.DbCtx,
 		db.UpdateFormByIDParams{
@@ -336,6 +453,9 @@ func UpdateForm(c echo.Context) error {
 			MaxResponses:      payload.MaxResponses,
 			IndividualLimit:   payload.IndividualLimit,
 			EditableResponses: payload.EditableResponses,
+			EditDeadline:      payload.EditDeadline,
+			EditWindow:        payload.EditWindow,
+			Quiz:              payload.Quiz,
 		},
 	)
 
@@ -347,6 +467,19 @@ func UpdateForm(c echo.Context) error {
 				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
 			)
 		}
+		if errors.As(err, &pgErr) && pgErr.Hint == "already-exists" {
+			return c.JSON(
+				http.StatusConflict,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		if errors.As(err, &pgErr) && pgErr.Hint == "reserved-slug" {
+			return c.JSON(
+				http.StatusUnprocessableEntity,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
 
 		log.Error("failed to update form", "error", err)
 		return c.JSON(
@@ -390,3 +523,14 @@ func DeleteForm(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+// invalidStructure sends the errors found while parsing a structure.
+func invalidStructure(c echo.Context, err error, message string) error {
+	var errs spec.Errors
+	errors.As(err, &errs)
+
+	return c.JSON(
+		http.StatusUnprocessableEntity,
+		utils.FromErrors(utils.ErrorInvalidStructure, errors.New(message), errs),
+	)
+}
@@ -6,10 +6,12 @@ import (
 	"backend/utility"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/charmbracelet/log"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/labstack/echo/v4"
 )
 
@@ -43,7 +45,39 @@ func ListPermissions(c echo.Context) error {
 		)
 	}
 
-	return c.JSON(http.StatusOK, utils.EmptyArrayIfNull(permissions))
+	now := time.Now()
+	validities := make([]permissionValidity, 0, len(permissions))
+	for _, permission := range permissions {
+		validities = append(validities, newPermissionValidity(permission, now))
+	}
+
+	return c.JSON(http.StatusOK, validities)
+}
+
+// permissionValidity is a permission along with whether it is in effect, and
+// for how many more seconds it will be, if it expires.
+type permissionValidity struct {
+	db.FormPermission
+	Active    bool   `json:"active"`
+	Remaining *int64 `json:"remaining"`
+}
+
+func newPermissionValidity(permission db.FormPermission, now time.Time) permissionValidity {
+	validity := permissionValidity{FormPermission: permission, Active: true}
+
+	if permission.Starts != nil && permission.Starts.Valid && permission.Starts.Time.After(now) {
+		validity.Active = false
+	}
+
+	if permission.Expires != nil && permission.Expires.Valid {
+		remaining := max(int64(permission.Expires.Time.Sub(now).Seconds()), 0)
+		validity.Remaining = &remaining
+		if remaining == 0 {
+			validity.Active = false
+		}
+	}
+
+	return validity
 }
 
 func GrantPermission(c echo.Context) error {
@@ -53,9 +87,13 @@ func GrantPermission(c echo.Context) error {
 	formID := c.Param("formId")
 
 	type Payload struct {
-		Role  db.PermissionRole `json:"role" validate:"oneof=view respond comment analyze edit manage"`
-		User  *string           `json:"user"`
-		Group *string           `json:"group"`
+		Role   db.PermissionRole `json:"role" validate:"oneof=view respond comment analyze edit manage"`
+		User   *string           `json:"user"`
+		Group  *string           `json:"group"`
+		Anyone bool              `json:"anyone"`
+		// the role is only in effect between these times, if given
+		Starts  *pgtype.Timestamptz `json:"starts"`
+		Expires *pgtype.Timestamptz `json:"expires"`
 	}
 
 	payload := Payload{}
@@ -81,6 +119,26 @@ func GrantPermission(c echo.Context) error {
 		)
 	}
 
+	if payload.Anyone && payload.Role != db.PermissionRoleView && payload.Role != db.PermissionRoleRespond {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to process payload - anyone with the link can only be given the view or respond role."),
+			),
+		)
+	}
+
+	if payload.Starts != nil && payload.Expires != nil && !payload.Expires.Time.After(payload.Starts.Time) {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to process payload - expires must be after starts."),
+			),
+		)
+	}
+
 	permission, err := cc.Query.GrantPermission(
 		*cc.DbCtx,
 		db.GrantPermissionParams{
@@ -88,7 +146,10 @@ func GrantPermission(c echo.Context) error {
 			FormID:      formID,
 			TargetUser:  payload.User,
 			TargetGroup: payload.Group,
+			Anyone:      payload.Anyone,
 			Role:        payload.Role,
+			Starts:      payload.Starts,
+			Expires:     payload.Expires,
 		},
 	)
 
@@ -100,7 +161,7 @@ func GrantPermission(c echo.Context) error {
 					http.StatusUnprocessableEntity,
 					utils.FromError(
 						utils.ErrorBadRequest,
-						errors.New("Failed to process payload - either a user ID or a group ID must be specified, but not both."),
+						errors.New("Failed to process payload - exactly one of a user, a group or anyone must be specified."),
 					),
 				)
 			}
@@ -112,11 +173,9 @@ func GrantPermission(c echo.Context) error {
 				)
 			}
 
-			if pgErr.Hint == "not-found" {
-				return c.JSON(
-					http.StatusNotFound,
-					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
-				)
+			// users who have never logged in are given the role when they do
+			if pgErr.Hint == "not-found" && payload.User != nil {
+				return invitePermission(c, formID, *payload.User, payload.Role, payload.Starts, payload.Expires)
 			}
 		}
 
@@ -130,6 +189,44 @@ func GrantPermission(c echo.Context) error {
 	return c.JSON(http.StatusCreated, permission)
 }
 
+func invitePermission(
+	c echo.Context, formID, email string, role db.PermissionRole,
+	starts, expires *pgtype.Timestamptz,
+) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	invite, err := cc.Query.InviteToForm(
+		*cc.DbCtx,
+		db.InviteToFormParams{
+			FormID:  formID,
+			UserID:  user.ID,
+			Email:   email,
+			Role:    role,
+			Starts:  starts,
+			Expires: expires,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to invite to form", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to grant permission.")),
+		)
+	}
+
+	return c.JSON(http.StatusAccepted, invite)
+}
+
 func RevokePermission(c echo.Context) error {
 	cc := c.(*dbcontext.Context)
 	user := c.Get("user").(db.User)
@@ -164,3 +261,150 @@ func RevokePermission(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+func ListInvites(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	invites, err := cc.Query.ListFormInvites(
+		*cc.DbCtx,
+		db.ListFormInvitesParams{
+			FormID: formID,
+			UserID: user.ID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to fetch form invites", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve form invites.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, utils.EmptyArrayIfNull(invites))
+}
+
+func CancelInvite(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+	inviteID := c.Param("inviteId")
+
+	err := cc.Query.CancelFormInvite(
+		*cc.DbCtx,
+		db.CancelFormInviteParams{
+			FormID:   formID,
+			UserID:   user.ID,
+			InviteID: inviteID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to cancel form invite", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to cancel form invite.")),
+		)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// GetMyPermissions describes what the current user, or guest, may do on a
+// form, so that clients do not have to find out by trying.
+func GetMyPermissions(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+
+	formID := c.Param("formId")
+
+	var userID *string
+	token := ""
+	if user, ok := c.Get("user").(db.User); ok {
+		userID = &user.ID
+		token = utils.ParticipantToken(formID, user.ID)
+	} else {
+		token = utils.ParticipantToken(formID, "guest:"+c.Get("guest").(string))
+	}
+
+	access, err := cc.Query.GetFormAccess(
+		*cc.DbCtx,
+		db.GetFormAccessParams{
+			FormID: formID,
+			UserID: userID,
+			Token:  token,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to fetch form access", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve permissions.")),
+		)
+	}
+
+	sources, err := cc.Query.ListPermissionSources(
+		*cc.DbCtx,
+		db.ListPermissionSourcesParams{
+			UserID: userID,
+			FormID: formID,
+		},
+	)
+
+	if err != nil {
+		log.Error("failed to fetch permission sources", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve permissions.")),
+		)
+	}
+
+	// the grants may have been revoked since access was checked
+	if len(sources) == 0 {
+		return c.JSON(
+			http.StatusForbidden,
+			utils.FromError(
+				utils.ErrorForbidden,
+				errors.New("Form not found or you do not have permission do this."),
+			),
+		)
+	}
+
+	// sources are ordered by role, so the first holds the effective role
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"role":             sources[0].Role,
+		"roles":            sources,
+		"open":             access.Open,
+		"reason":           access.Reason,
+		"individual_limit": access.IndividualLimit,
+		"remaining":        access.Remaining,
+	})
+}
@@ -0,0 +1,99 @@
+package forms
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/spec"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+// redactAnswerKey removes the answer key from the structure of a form, unless
+// the user is allowed to edit the form. Guests, with no user id, never are.
+// Forms that are not quizzes may still have an answer key, from when they were
+// or before they become one, so it is removed from those too.
+func redactAnswerKey(cc *dbcontext.Context, userID *string, form *db.Form) error {
+	if userID != nil {
+		canEdit, err := cc.Query.HasFormPermission(
+			*cc.DbCtx,
+			db.HasFormPermissionParams{
+				UserID: *userID,
+				FormID: form.ID,
+				Role:   db.PermissionRoleEdit,
+			},
+		)
+		if err != nil || canEdit {
+			return err
+		}
+	}
+
+	var err error
+	form.Structure, err = spec.Redact(form.Structure)
+	return err
+}
+
+func ReleaseScores(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Payload struct {
+		Released *bool `json:"released" validate:"required"`
+	}
+
+	var payload Payload
+
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	form, err := cc.Query.ReleaseScores(
+		*cc.DbCtx,
+		db.ReleaseScoresParams{
+			ID:       formID,
+			UserID:   user.ID,
+			Released: *payload.Released,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to release scores", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to release scores.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, form)
+}
@@ -0,0 +1,169 @@
+package forms
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func ListTransfers(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	transfers, err := cc.Query.ListTransfers(*cc.DbCtx, user.ID)
+	if err != nil {
+		log.Error("failed to fetch form transfers", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve form transfers.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, utils.EmptyArrayIfNull(transfers))
+}
+
+func TransferForm(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Payload struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	payload := Payload{}
+
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	transfer, err := cc.Query.OfferFormTransfer(
+		*cc.DbCtx,
+		db.OfferFormTransferParams{
+			FormID:     formID,
+			UserID:     user.ID,
+			TargetUser: payload.Email,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Hint {
+			case "forbidden":
+				return c.JSON(
+					http.StatusForbidden,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			case "not-found":
+				return c.JSON(
+					http.StatusNotFound,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			case "already-exists":
+				return c.JSON(
+					http.StatusConflict,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			}
+		}
+
+		log.Error("failed to offer form transfer", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to transfer form.")),
+		)
+	}
+
+	return c.JSON(http.StatusAccepted, transfer)
+}
+
+func AcceptTransfer(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	form, err := cc.Query.AcceptFormTransfer(
+		*cc.DbCtx,
+		db.AcceptFormTransferParams{
+			FormID: formID,
+			UserID: user.ID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to accept form transfer", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to transfer form.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, form)
+}
+
+func CancelTransfer(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	err := cc.Query.CancelFormTransfer(
+		*cc.DbCtx,
+		db.CancelFormTransferParams{
+			FormID: formID,
+			UserID: user.ID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to cancel form transfer", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to cancel form transfer.")),
+		)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
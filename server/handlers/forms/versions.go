@@ -0,0 +1,307 @@
+package forms
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/spec"
+	"backend/utility"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func ListVersions(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Query struct {
+		Limit  int32 `query:"limit" validate:"gte=1,lte=100"`
+		Offset int32 `query:"offset" validate:"gte=0"`
+	}
+
+	query := Query{Limit: 20}
+
+	err := c.Bind(&query)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	err = utils.Validate.Struct(query)
+	if err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	versions, err := cc.Query.ListFormVersions(
+		*cc.DbCtx,
+		db.ListFormVersionsParams{
+			FormID:    formID,
+			UserID:    user.ID,
+			LimitVal:  query.Limit,
+			OffsetVal: query.Offset,
+		},
+	)
+	if err != nil {
+		return versionError(c, err, "Failed to fetch form versions.")
+	}
+
+	total, err := cc.Query.CountFormVersions(
+		*cc.DbCtx,
+		db.CountFormVersionsParams{
+			FormID: formID,
+			UserID: user.ID,
+		},
+	)
+	if err != nil {
+		return versionError(c, err, "Failed to count form versions.")
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": utils.EmptyArrayIfNull(versions),
+		"pagination": map[string]int64{
+			"offset": int64(query.Offset),
+			"limit":  int64(query.Limit),
+			"total":  total,
+		},
+	})
+}
+
+func GetVersion(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Query struct {
+		Version int32 `param:"version" validate:"gte=1"`
+	}
+
+	var query Query
+
+	err := c.Bind(&query)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	err = utils.Validate.Struct(query)
+	if err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	version, err := cc.Query.GetFormVersion(
+		*cc.DbCtx,
+		db.GetFormVersionParams{
+			FormID:  formID,
+			UserID:  user.ID,
+			Version: query.Version,
+		},
+	)
+	if err != nil {
+		return versionError(c, err, "Failed to fetch form version.")
+	}
+
+	return c.JSON(http.StatusOK, version)
+}
+
+func DiffVersions(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Query struct {
+		Version int32  `param:"version" validate:"gte=1"`
+		From    *int32 `query:"from" validate:"omitempty,gte=1"`
+	}
+
+	var query Query
+
+	err := c.Bind(&query)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	err = utils.Validate.Struct(query)
+	if err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	// by default, a version is compared against the one before it
+	from := query.Version - 1
+	if query.From != nil {
+		from = *query.From
+	}
+
+	if from < 1 {
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to process payload - the first version has nothing to compare against."),
+			),
+		)
+	}
+
+	structures := make([]*spec.Form, 2)
+	for i, number := range []int32{from, query.Version} {
+		version, err := cc.Query.GetFormVersion(
+			*cc.DbCtx,
+			db.GetFormVersionParams{
+				FormID:  formID,
+				UserID:  user.ID,
+				Version: number,
+			},
+		)
+		if err != nil {
+			return versionError(c, err, "Failed to compare form versions.")
+		}
+
+		structures[i], err = spec.Parse(version.Structure)
+		if err != nil {
+			return invalidStructure(c, err, fmt.Sprintf("Version %d of the form has an invalid structure.", number))
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"from":    from,
+		"to":      query.Version,
+		"changes": spec.Diff(structures[0], structures[1]),
+	})
+}
+
+func RestoreVersion(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Query struct {
+		Version int32 `param:"version" validate:"gte=1"`
+	}
+
+	var query Query
+
+	err := c.Bind(&query)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	err = utils.Validate.Struct(query)
+	if err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	version, err := cc.Query.GetFormVersion(
+		*cc.DbCtx,
+		db.GetFormVersionParams{
+			FormID:  formID,
+			UserID:  user.ID,
+			Version: query.Version,
+		},
+	)
+	if err != nil {
+		return versionError(c, err, "Failed to restore form version.")
+	}
+
+	// older versions may not satisfy rules that were added since
+	if _, err := spec.Parse(version.Structure); err != nil {
+		return invalidStructure(c, err, fmt.Sprintf("Version %d of the form has an invalid structure.", query.Version))
+	}
+
+	// the old structure is saved as a new version, like any other edit
+	form, err := cc.Query.UpdateFormByID(
+		*cc.DbCtx,
+		db.UpdateFormByIDParams{
+			ID:        formID,
+			UserID:    user.ID,
+			Structure: &version.Structure,
+		},
+	)
+	if err != nil {
+		return versionError(c, err, "Failed to restore form version.")
+	}
+
+	return c.JSON(http.StatusOK, form)
+}
+
+// versionError sends the response for an error returned by one of the form
+// version queries.
+func versionError(c echo.Context, err error, message string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+		return c.JSON(
+			http.StatusForbidden,
+			utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+		)
+	}
+
+	if errors.As(err, &pgErr) && pgErr.Hint == "not-found" {
+		return c.JSON(
+			http.StatusNotFound,
+			utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+		)
+	}
+
+	log.Error("failed to process form versions", "error", err)
+	return c.JSON(
+		http.StatusInternalServerError,
+		utils.FromError(utils.ErrorInternal, errors.New(message)),
+	)
+}
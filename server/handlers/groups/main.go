@@ -162,16 +162,6 @@ func CreateGroup(c echo.Context) error {
 			)
 		}
 
-		if errors.As(err, &pgErr) && pgErr.Hint == "not-found" {
-			return c.JSON(
-				http.StatusNotFound,
-				utils.FromError(
-					utils.HttpErrorCode(pgErr.Hint),
-					errors.New(pgErr.Message),
-				),
-			)
-		}
-
 		log.Error("failed to create group", "error", err)
 		return c.JSON(
 			http.StatusInternalServerError,
@@ -405,13 +395,43 @@ func AddGroupMember(c echo.Context) error {
 
 	if err != nil {
 		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Hint == "not-found" {
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
 			return c.JSON(
-				http.StatusNotFound,
+				http.StatusForbidden,
 				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
 			)
 		}
 
+		// users who have never logged in join the group when they do
+		if errors.As(err, &pgErr) && pgErr.Hint == "not-found" {
+			return inviteMember(c, groupID, payload.Email)
+		}
+
+		log.Error("failed to add group member", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to add group member.")),
+		)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func inviteMember(c echo.Context, groupID, email string) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	invite, err := cc.Query.InviteToGroup(
+		*cc.DbCtx,
+		db.InviteToGroupParams{
+			GroupID: groupID,
+			UserID:  user.ID,
+			Email:   email,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
 			return c.JSON(
 				http.StatusForbidden,
@@ -419,14 +439,14 @@ func AddGroupMember(c echo.Context) error {
 			)
 		}
 
-		log.Error("failed to add group member", "error", err)
+		log.Error("failed to invite group member", "error", err)
 		return c.JSON(
 			http.StatusInternalServerError,
 			utils.FromError(utils.ErrorInternal, errors.New("Failed to add group member.")),
 		)
 	}
 
-	return c.NoContent(http.StatusNoContent)
+	return c.JSON(http.StatusAccepted, invite)
 }
 
 func RemoveGroupMember(c echo.Context) error {
@@ -463,3 +483,71 @@ func RemoveGroupMember(c echo.Context) error {
 
 	return c.NoContent(http.StatusNoContent)
 }
+
+func ListGroupInvites(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+
+	invites, err := cc.Query.ListGroupInvites(
+		*cc.DbCtx,
+		db.ListGroupInvitesParams{
+			GroupID: groupID,
+			UserID:  user.ID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to fetch group invites", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve group invites.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, utils.EmptyArrayIfNull(invites))
+}
+
+func CancelGroupInvite(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+	inviteID := c.Param("inviteId")
+
+	err := cc.Query.CancelGroupInvite(
+		*cc.DbCtx,
+		db.CancelGroupInviteParams{
+			GroupID:  groupID,
+			UserID:   user.ID,
+			InviteID: inviteID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to cancel group invite", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to cancel group invite.")),
+		)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
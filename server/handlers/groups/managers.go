@@ -0,0 +1,235 @@
+package groups
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func ListGroupManagers(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+
+	managers, err := cc.Query.ListGroupManagers(
+		*cc.DbCtx,
+		db.ListGroupManagersParams{
+			GroupID: groupID,
+			UserID:  user.ID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to fetch group managers", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve group managers.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, utils.EmptyArrayIfNull(managers))
+}
+
+func AddGroupManager(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+
+	type Payload struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	payload := Payload{}
+
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	manager, err := cc.Query.AddGroupManager(
+		*cc.DbCtx,
+		db.AddGroupManagerParams{
+			GroupID:    groupID,
+			UserID:     user.ID,
+			TargetUser: payload.Email,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Hint {
+			case "forbidden":
+				return c.JSON(
+					http.StatusForbidden,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			case "not-found":
+				return c.JSON(
+					http.StatusNotFound,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			case "already-exists":
+				return c.JSON(
+					http.StatusConflict,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			}
+		}
+
+		log.Error("failed to add group manager", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to add group manager.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, manager)
+}
+
+func RemoveGroupManager(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+	targetUserID := c.Param("userId")
+
+	err := cc.Query.RemoveGroupManager(
+		*cc.DbCtx,
+		db.RemoveGroupManagerParams{
+			GroupID:      groupID,
+			UserID:       user.ID,
+			TargetUserID: targetUserID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to remove group manager", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to remove group manager.")),
+		)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+func TransferGroup(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+
+	type Payload struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	payload := Payload{}
+
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	group, err := cc.Query.TransferGroup(
+		*cc.DbCtx,
+		db.TransferGroupParams{
+			ID:         groupID,
+			UserID:     user.ID,
+			TargetUser: payload.Email,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return c.JSON(
+				http.StatusConflict,
+				utils.FromError(
+					utils.ErrorConflict,
+					errors.New("The new owner already has a group with the same name."),
+				),
+			)
+		}
+
+		if errors.As(err, &pgErr) {
+			switch pgErr.Hint {
+			case "forbidden":
+				return c.JSON(
+					http.StatusForbidden,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			case "not-found":
+				return c.JSON(
+					http.StatusNotFound,
+					utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+				)
+			}
+		}
+
+		log.Error("failed to transfer group", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to transfer group.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, group)
+}
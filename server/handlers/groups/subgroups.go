@@ -0,0 +1,151 @@
+package groups
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"net/http"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func ListSubgroups(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+
+	subgroups, err := cc.Query.ListSubgroups(
+		*cc.DbCtx,
+		db.ListSubgroupsParams{
+			GroupID: groupID,
+			UserID:  user.ID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to fetch subgroups", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to retrieve subgroups.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, utils.EmptyArrayIfNull(subgroups))
+}
+
+func AddSubgroup(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+
+	type Payload struct {
+		Group   string `json:"group" validate:"required"`
+		Exclude bool   `json:"exclude"`
+	}
+
+	payload := Payload{}
+
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	subgroup, err := cc.Query.AddSubgroup(
+		*cc.DbCtx,
+		db.AddSubgroupParams{
+			GroupID:    groupID,
+			UserID:     user.ID,
+			SubgroupID: payload.Group,
+			Exclude:    payload.Exclude,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		if errors.As(err, &pgErr) && pgErr.Hint == "group-cycle" {
+			return c.JSON(
+				http.StatusConflict,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to add subgroup", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to add subgroup.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, subgroup)
+}
+
+func RemoveSubgroup(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	groupID := c.Param("groupId")
+	subgroupID := c.Param("subgroupId")
+
+	err := cc.Query.RemoveSubgroup(
+		*cc.DbCtx,
+		db.RemoveSubgroupParams{
+			GroupID:    groupID,
+			UserID:     user.ID,
+			SubgroupID: subgroupID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to remove subgroup", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to remove subgroup.")),
+		)
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
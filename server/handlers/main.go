@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"backend/handlers/admin"
 	"backend/handlers/auth"
 	"backend/handlers/comments"
 	"backend/handlers/forms"
@@ -17,19 +18,36 @@ func RegisterAll(router *echo.Group) {
 	router.GET("/auth/login/callback", auth.Callback)
 	router.GET("/auth/logout", auth.Logout)
 	router.GET("/auth/info", middleware.Auth(auth.Info))
+	router.GET("/auth/sessions", middleware.Auth(auth.ListSessions))
+	router.DELETE("/auth/sessions", middleware.Auth(auth.RevokeAllSessions))
+	router.DELETE("/auth/sessions/:sessionId", middleware.Auth(auth.RevokeSession))
 
 	router.GET("/users/:userId", middleware.Auth(users.GetUser))
 
 	router.GET("/forms", middleware.Auth(forms.ListForms))
 	router.POST("/forms", middleware.Auth(forms.CreateForm))
+	router.GET("/forms/transfers", middleware.Auth(forms.ListTransfers))
 
 	router.GET("/forms/:formId", middleware.Auth(forms.GetForm))
 	router.PATCH("/forms/:formId", middleware.Auth(forms.UpdateForm))
 	router.DELETE("/forms/:formId", middleware.Auth(forms.DeleteForm))
+	router.PUT("/forms/:formId/scores", middleware.Auth(forms.ReleaseScores))
+	router.POST("/forms/:formId/transfer", middleware.Auth(forms.TransferForm))
+	router.DELETE("/forms/:formId/transfer", middleware.Auth(forms.CancelTransfer))
+	router.POST("/forms/:formId/transfer/accept", middleware.Auth(forms.AcceptTransfer))
 
+	router.GET("/forms/:formId/versions", middleware.Auth(forms.ListVersions))
+	router.GET("/forms/:formId/versions/:version", middleware.Auth(forms.GetVersion))
+	router.GET("/forms/:formId/versions/:version/diff", middleware.Auth(forms.DiffVersions))
+	router.POST("/forms/:formId/versions/:version/restore", middleware.Auth(forms.RestoreVersion))
+
+	router.GET("/forms/:formId/me", middleware.OptionalAuth(forms.GetMyPermissions))
+	router.GET("/forms/:formId/audit", middleware.Auth(forms.ListAudit))
 	router.GET("/forms/:formId/permissions", middleware.Auth(forms.ListPermissions))
 	router.POST("/forms/:formId/permissions", middleware.Auth(forms.GrantPermission))
 	router.DELETE("/forms/:formId/permissions/:permissionId", middleware.Auth(forms.RevokePermission))
+	router.GET("/forms/:formId/invites", middleware.Auth(forms.ListInvites))
+	router.DELETE("/forms/:formId/invites/:inviteId", middleware.Auth(forms.CancelInvite))
 
 	router.GET("/forms/:formId/comments", middleware.Auth(comments.ListComments))
 	router.POST("/forms/:formId/comments", middleware.Auth(comments.CreateComment))
@@ -37,14 +55,19 @@ func RegisterAll(router *echo.Group) {
 	router.DELETE("/forms/:formId/comments/:commentId", middleware.Auth(comments.DeleteComment))
 
 	router.GET("/forms/:formId/responses", middleware.Auth(responses.ListResponses))
-	router.POST("/forms/:formId/responses", middleware.Auth(responses.StartResponse))
-	router.GET("/forms/:formId/responses/:responseId", middleware.Auth(responses.GetResponse))
-	router.GET("/forms/:formId/responses/:responseId/answers", middleware.Auth(responses.GetAnswers))
-	router.PUT("/forms/:formId/responses/:responseId/answers", middleware.Auth(responses.SaveAnswer))
-	router.POST("/forms/:formId/responses/:responseId/submit", middleware.Auth(responses.SubmitResponse))
+	router.POST("/forms/:formId/responses", middleware.OptionalAuth(responses.StartResponse))
+	router.GET("/forms/:formId/responses/export", middleware.Auth(responses.ExportResponses))
+	router.GET("/forms/:formId/analytics", middleware.Auth(responses.GetAnalytics))
+	router.GET("/forms/:formId/responses/:responseId", middleware.OptionalAuth(responses.GetResponse))
+	router.GET("/forms/:formId/responses/:responseId/answers", middleware.OptionalAuth(responses.GetAnswers))
+	router.PUT("/forms/:formId/responses/:responseId/answers", middleware.OptionalAuth(responses.SaveAnswer))
+	router.PATCH("/forms/:formId/responses/:responseId/answers", middleware.OptionalAuth(responses.SaveAnswers))
+	router.GET("/forms/:formId/responses/:responseId/history", middleware.Auth(responses.GetAnswerHistory))
+	router.GET("/forms/:formId/responses/:responseId/visible", middleware.OptionalAuth(responses.GetVisibleQuestions))
+	router.POST("/forms/:formId/responses/:responseId/submit", middleware.OptionalAuth(responses.SubmitResponse))
 
 	// This route is placed later so it gets checked last.
-	router.GET("/forms/:handle/:slug", middleware.Auth(forms.ResolveForm))
+	router.GET("/forms/:handle/:slug", middleware.OptionalAuth(forms.ResolveForm))
 
 	router.GET("/responses/saved", middleware.Auth(responses.ListSavedResponses))
 
@@ -58,4 +81,22 @@ func RegisterAll(router *echo.Group) {
 	router.PUT("/groups/:groupId/domain", middleware.Auth(groups.UpdateGroupDomain))
 	router.POST("/groups/:groupId/members", middleware.Auth(groups.AddGroupMember))
 	router.DELETE("/groups/:groupId/members/:userId", middleware.Auth(groups.RemoveGroupMember))
+	router.GET("/groups/:groupId/managers", middleware.Auth(groups.ListGroupManagers))
+	router.POST("/groups/:groupId/managers", middleware.Auth(groups.AddGroupManager))
+	router.DELETE("/groups/:groupId/managers/:userId", middleware.Auth(groups.RemoveGroupManager))
+	router.POST("/groups/:groupId/transfer", middleware.Auth(groups.TransferGroup))
+	router.GET("/groups/:groupId/subgroups", middleware.Auth(groups.ListSubgroups))
+	router.POST("/groups/:groupId/subgroups", middleware.Auth(groups.AddSubgroup))
+	router.DELETE("/groups/:groupId/subgroups/:subgroupId", middleware.Auth(groups.RemoveSubgroup))
+	router.GET("/groups/:groupId/invites", middleware.Auth(groups.ListGroupInvites))
+	router.DELETE("/groups/:groupId/invites/:inviteId", middleware.Auth(groups.CancelGroupInvite))
+
+	router.GET("/admin/forms", middleware.Admin(admin.ListForms))
+	router.PUT("/admin/forms/:formId/owner", middleware.Admin(admin.ReassignForm))
+	router.DELETE("/admin/forms/:formId", middleware.Admin(admin.DeleteForm))
+	router.GET("/admin/groups", middleware.Admin(admin.ListGroups))
+	router.PUT("/admin/groups/:groupId/owner", middleware.Admin(admin.ReassignGroup))
+	router.GET("/admin/users", middleware.Admin(admin.ListUsers))
+	router.PUT("/admin/users/:userId/disabled", middleware.Admin(admin.SetUserDisabled))
+	router.GET("/admin/audit", middleware.Admin(admin.ListAudit))
 }
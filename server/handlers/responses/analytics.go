@@ -0,0 +1,333 @@
+package responses
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/spec"
+	"backend/utility"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+// analyticsSamples is the number of recent answers included for text questions.
+const analyticsSamples = 5
+
+type choiceStats struct {
+	Value      string  `json:"value"`
+	Label      string  `json:"label,omitempty"` // empty for values no longer offered
+	Count      int64   `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+type categoryStats struct {
+	Category string        `json:"category"`
+	Label    string        `json:"label,omitempty"`
+	Answered int64         `json:"answered"`
+	Options  []choiceStats `json:"options"`
+}
+
+type rangeStats struct {
+	Mean *float64 `json:"mean"`
+	Min  *int32   `json:"min"`
+	Max  *int32   `json:"max"`
+}
+
+type questionStats struct {
+	ID       string            `json:"id"`
+	Type     spec.QuestionType `json:"type"`
+	Title    string            `json:"title"`
+	Answered int64             `json:"answered"`
+
+	// radio, checkbox, select and likert questions
+	Options []choiceStats `json:"options,omitempty"`
+
+	// likert questions
+	Mean   *float64 `json:"mean,omitempty"`
+	Median *float64 `json:"median,omitempty"`
+
+	// matrix questions
+	Categories []categoryStats `json:"categories,omitempty"`
+
+	// input and textarea questions
+	Characters *rangeStats `json:"characters,omitempty"`
+	Words      *rangeStats `json:"words,omitempty"`
+	Samples    []string    `json:"samples,omitempty"`
+}
+
+func GetAnalytics(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Query struct {
+		Status db.ResponseStatus `query:"status" validate:"oneof=draft completed edited"`
+	}
+
+	query := Query{Status: "completed"}
+
+	err := c.Bind(&query)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	err = utils.Validate.Struct(query)
+	if err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	form, err := cc.Query.GetFormForAnalysis(
+		*cc.DbCtx,
+		db.GetFormForAnalysisParams{
+			FormID: formID,
+			UserID: user.ID,
+		},
+	)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	structure, err := spec.Parse(form.Structure)
+	if err != nil {
+		return analyticsError(c, fmt.Errorf("failed to parse structure of form %s: %w", form.ID, err))
+	}
+
+	var choices, numbers, texts []string
+	for _, q := range structure.Questions() {
+		switch q.Type {
+		case spec.TypeRadio, spec.TypeCheckbox, spec.TypeSelect, spec.TypeMatrix:
+			choices = append(choices, q.ID)
+		case spec.TypeLikert:
+			choices = append(choices, q.ID)
+			numbers = append(numbers, q.ID)
+		case spec.TypeInput, spec.TypeTextarea:
+			texts = append(texts, q.ID)
+		}
+	}
+
+	total, err := cc.Query.CountResponses(
+		*cc.DbCtx,
+		db.CountResponsesParams{
+			FormID: formID,
+			UserID: user.ID,
+			Status: query.Status,
+		},
+	)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	choiceCounts, err := cc.Query.CountChoices(
+		*cc.DbCtx,
+		db.CountChoicesParams{
+			FormID:    formID,
+			UserID:    user.ID,
+			Status:    query.Status,
+			Questions: utils.EmptyArrayIfNull(choices),
+		},
+	)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	numberSummaries, err := cc.Query.SummarizeNumbers(
+		*cc.DbCtx,
+		db.SummarizeNumbersParams{
+			FormID:    formID,
+			UserID:    user.ID,
+			Status:    query.Status,
+			Questions: utils.EmptyArrayIfNull(numbers),
+		},
+	)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	textSummaries, err := cc.Query.SummarizeText(
+		*cc.DbCtx,
+		db.SummarizeTextParams{
+			FormID:    formID,
+			UserID:    user.ID,
+			Status:    query.Status,
+			Questions: utils.EmptyArrayIfNull(texts),
+			Samples:   analyticsSamples,
+		},
+	)
+	if err != nil {
+		return analyticsError(c, err)
+	}
+
+	counts := map[string][]db.ChoiceCount{}
+	for _, count := range choiceCounts {
+		counts[count.Question] = append(counts[count.Question], count)
+	}
+
+	summaries := map[string]db.NumberSummary{}
+	for _, summary := range numberSummaries {
+		summaries[summary.Question] = summary
+	}
+
+	textStats := map[string]db.TextSummary{}
+	for _, summary := range textSummaries {
+		textStats[summary.Question] = summary
+	}
+
+	questions := []questionStats{}
+	for _, q := range structure.Questions() {
+		if q.Type == spec.TypeSectionHeader {
+			continue
+		}
+
+		stats := questionStats{ID: q.ID, Type: q.Type, Title: q.Title}
+		if rows := counts[q.ID]; len(rows) > 0 {
+			stats.Answered = rows[0].Answered
+		}
+
+		switch q.Type {
+		case spec.TypeRadio, spec.TypeCheckbox, spec.TypeSelect:
+			stats.Options = choiceBreakdown(q.Options, counts[q.ID], nil, stats.Answered)
+		case spec.TypeLikert:
+			stats.Options = choiceBreakdown(likertSteps(q), counts[q.ID], nil, stats.Answered)
+
+			summary := summaries[q.ID]
+			stats.Mean, stats.Median = round(summary.Mean), round(summary.Median)
+		case spec.TypeMatrix:
+			stats.Categories = matrixBreakdown(q, counts[q.ID])
+		case spec.TypeInput, spec.TypeTextarea:
+			summary := textStats[q.ID]
+			stats.Answered = summary.Answered
+			stats.Characters = &rangeStats{round(summary.MeanChars), summary.MinChars, summary.MaxChars}
+			stats.Words = &rangeStats{round(summary.MeanWords), summary.MinWords, summary.MaxWords}
+			stats.Samples = utils.EmptyArrayIfNull(summary.Samples)
+		}
+
+		questions = append(questions, stats)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"responses": total,
+		"questions": questions,
+	})
+}
+
+// choiceBreakdown lists the number of times each option was picked, out of the
+// number of answers given, in the order of the options. Values that are not
+// options, such as those removed in a later version of the form, come last.
+// Only counts for the given category are used.
+func choiceBreakdown(options []spec.Option, counts []db.ChoiceCount, category *string, answered int64) []choiceStats {
+	picked := map[string]int64{}
+	for _, count := range counts {
+		if (count.Category == nil) != (category == nil) || category != nil && *count.Category != *category {
+			continue
+		}
+		picked[count.Value] += count.Count
+	}
+
+	stats := []choiceStats{}
+	for _, option := range options {
+		stats = append(stats, choiceStats{
+			Value:      option.Value,
+			Label:      option.Label,
+			Count:      picked[option.Value],
+			Percentage: percentage(picked[option.Value], answered),
+		})
+		delete(picked, option.Value)
+	}
+
+	for _, count := range counts {
+		if n, ok := picked[count.Value]; ok {
+			stats = append(stats, choiceStats{
+				Value:      count.Value,
+				Count:      n,
+				Percentage: percentage(n, answered),
+			})
+			delete(picked, count.Value)
+		}
+	}
+
+	return stats
+}
+
+// likertSteps lists every step of a likert question, so that steps nobody
+// picked are still counted. The number of steps is capped by the parser.
+func likertSteps(q *spec.Question) []spec.Option {
+	steps := make([]spec.Option, q.Steps)
+	for i := range steps {
+		steps[i] = spec.Option{Value: strconv.Itoa(i + 1)}
+	}
+	return steps
+}
+
+func matrixBreakdown(q *spec.Question, counts []db.ChoiceCount) []categoryStats {
+	stats := []categoryStats{}
+	for _, category := range q.Categories {
+		// each answer picks a single option per category
+		var answered int64
+		for _, count := range counts {
+			if count.Category != nil && *count.Category == category.Value {
+				answered += count.Count
+			}
+		}
+
+		stats = append(stats, categoryStats{
+			Category: category.Value,
+			Label:    category.Label,
+			Answered: answered,
+			Options:  choiceBreakdown(q.Options, counts, &category.Value, answered),
+		})
+	}
+	return stats
+}
+
+// percentage rounds to two decimal places.
+func percentage(count, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(count)*10000/float64(total)) / 100
+}
+
+func round(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+
+	rounded := math.Round(*value*100) / 100
+	return &rounded
+}
+
+func analyticsError(c echo.Context, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+		return c.JSON(
+			http.StatusForbidden,
+			utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+		)
+	}
+
+	log.Error("failed to compute analytics", "error", err)
+	return c.JSON(
+		http.StatusInternalServerError,
+		utils.FromError(utils.ErrorInternal, errors.New("Failed to compute analytics.")),
+	)
+}
@@ -0,0 +1,46 @@
+package responses
+
+import (
+	"backend/db"
+	"backend/spec"
+	"testing"
+)
+
+func TestLikertBreakdown(t *testing.T) {
+	q := &spec.Question{ID: "scale", Type: spec.TypeLikert, Steps: 5}
+
+	tests := []struct {
+		name   string
+		counts []db.ChoiceCount
+		want   []int64
+	}{
+		{"nothing answered", nil, []int64{0, 0, 0, 0, 0}},
+		{
+			"top steps not picked",
+			[]db.ChoiceCount{
+				{Question: "scale", Value: "1", Count: 2, Answered: 3},
+				{Question: "scale", Value: "3", Count: 1, Answered: 3},
+			},
+			[]int64{2, 0, 1, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var answered int64
+			if len(tt.counts) > 0 {
+				answered = tt.counts[0].Answered
+			}
+
+			stats := choiceBreakdown(likertSteps(q), tt.counts, nil, answered)
+			if len(stats) != len(tt.want) {
+				t.Fatalf("choiceBreakdown() = %+v, want %d steps", stats, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if stats[i].Value != string(rune('1'+i)) || stats[i].Count != want {
+					t.Errorf("choiceBreakdown()[%d] = %+v, want step %d picked %d times", i, stats[i], i+1, want)
+				}
+			}
+		})
+	}
+}
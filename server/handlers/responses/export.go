@@ -0,0 +1,360 @@
+package responses
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/spec"
+	"backend/utility"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/labstack/echo/v4"
+	"github.com/xuri/excelize/v2"
+)
+
+// exportBatchSize is the number of responses fetched at a time while
+// exporting, so that large forms do not have to be held in memory at once.
+const exportBatchSize = 500
+
+func ExportResponses(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	user := c.Get("user").(db.User)
+
+	formID := c.Param("formId")
+
+	type Query struct {
+		Format string            `query:"format" validate:"oneof=csv xlsx"`
+		Status db.ResponseStatus `query:"status" validate:"oneof=draft completed edited"`
+	}
+
+	query := Query{Format: "csv", Status: "completed"}
+
+	err := c.Bind(&query)
+	if err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	err = utils.Validate.Struct(query)
+	if err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	form, err := cc.Query.GetFormForAnalysis(
+		*cc.DbCtx,
+		db.GetFormForAnalysisParams{
+			FormID: formID,
+			UserID: user.ID,
+		},
+	)
+	if err != nil {
+		return exportError(c, err)
+	}
+
+	versions, err := getVersions(cc, formID, user.ID)
+	if err != nil {
+		return exportError(c, err)
+	}
+
+	columns := spec.Columns(versions...)
+
+	header := []string{"id", "status", "version", "started", "submitted", "edited", "edited_answers"}
+	if form.Anonymous == nil || !*form.Anonymous {
+		header = append(header, "respondent_handle", "respondent_email")
+	}
+	if form.Quiz {
+		header = append(header, "score")
+	}
+	for _, column := range columns {
+		header = append(header, column.Header())
+	}
+
+	response := c.Response()
+	response.Header().Set(
+		echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=%q", form.Slug+"-responses."+query.Format),
+	)
+
+	var writer rowWriter
+	if query.Format == "xlsx" {
+		response.Header().Set(
+			echo.HeaderContentType,
+			"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		)
+		writer, err = newXlsxWriter(response)
+		if err != nil {
+			log.Error("failed to create spreadsheet", "error", err)
+			return c.JSON(
+				http.StatusInternalServerError,
+				utils.FromError(utils.ErrorInternal, errors.New("Failed to export responses.")),
+			)
+		}
+	} else {
+		response.Header().Set(echo.HeaderContentType, "text/csv; charset=utf-8")
+		writer = &csvWriter{writer: csv.NewWriter(response), response: response}
+	}
+
+	// from here on the response is streamed, so errors can only be logged
+	response.WriteHeader(http.StatusOK)
+
+	if err := writer.Write(header); err != nil {
+		log.Error("failed to export responses", "error", err)
+		return nil
+	}
+
+	var after *string
+	for {
+		rows, err := cc.Query.ExportResponses(
+			*cc.DbCtx,
+			db.ExportResponsesParams{
+				FormID:   formID,
+				UserID:   user.ID,
+				Status:   query.Status,
+				After:    after,
+				LimitVal: exportBatchSize,
+			},
+		)
+		if err != nil {
+			log.Error("failed to export responses", "error", err)
+			return nil
+		}
+
+		for _, row := range rows {
+			if err := writer.Write(exportRow(form, row, columns)); err != nil {
+				log.Error("failed to export responses", "error", err)
+				return nil
+			}
+		}
+
+		if err := writer.Flush(); err != nil {
+			log.Error("failed to export responses", "error", err)
+			return nil
+		}
+
+		if len(rows) < exportBatchSize {
+			break
+		}
+		after = &rows[len(rows)-1].ID
+	}
+
+	if err := writer.Close(); err != nil {
+		log.Error("failed to export responses", "error", err)
+	}
+
+	return nil
+}
+
+// getVersions fetches and parses every version of the structure of a form,
+// newest first. Versions that no longer parse are left out.
+func getVersions(cc *dbcontext.Context, formID, userID string) ([]*spec.Form, error) {
+	versions, err := cc.Query.ListVersionsForAnalysis(
+		*cc.DbCtx,
+		db.ListVersionsForAnalysisParams{
+			FormID: formID,
+			UserID: userID,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	structures := []*spec.Form{}
+	for _, version := range versions {
+		structure, err := spec.Parse(version.Structure)
+		if err != nil {
+			log.Warn("skipping invalid form version", "form", formID, "version", version.Version, "error", err)
+			continue
+		}
+		structures = append(structures, structure)
+	}
+
+	return structures, nil
+}
+
+func exportRow(form db.Form, row db.ResponseExport, columns []spec.Column) []string {
+	cells := []string{
+		row.ID,
+		string(row.Status),
+		strconv.Itoa(int(row.Version)),
+		formatTime(row.Started),
+		formatTime(row.Submitted),
+		formatTime(row.Edited),
+		strings.Join(row.EditedQuestions, "; "),
+	}
+
+	if form.Anonymous == nil || !*form.Anonymous {
+		cells = append(
+			cells,
+			neutralise(stringOrEmpty(row.RespondentHandle)),
+			neutralise(stringOrEmpty(row.RespondentEmail)),
+		)
+	}
+
+	if form.Quiz {
+		score := ""
+		if row.Score != nil {
+			score = strconv.Itoa(int(*row.Score))
+		}
+		cells = append(cells, score)
+	}
+
+	var answers map[string]json.RawMessage
+	if err := json.Unmarshal(row.Answers, &answers); err != nil {
+		log.Warn("failed to decode answers", "response", row.ID, "error", err)
+	}
+
+	values := make(map[string][]byte, len(answers))
+	for question, value := range answers {
+		values[question] = value
+	}
+
+	for _, column := range columns {
+		cells = append(cells, neutralise(column.Cell(values)))
+	}
+
+	return cells
+}
+
+// neutralise keeps spreadsheet programs from running a cell written by a
+// respondent as a formula, by making it start with a quote.
+func neutralise(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+func formatTime(t *pgtype.Timestamptz) string {
+	if t == nil || !t.Valid {
+		return ""
+	}
+	return t.Time.UTC().Format(time.RFC3339)
+}
+
+func stringOrEmpty(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func exportError(c echo.Context, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+		return c.JSON(
+			http.StatusForbidden,
+			utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+		)
+	}
+
+	log.Error("failed to export responses", "error", err)
+	return c.JSON(
+		http.StatusInternalServerError,
+		utils.FromError(utils.ErrorInternal, errors.New("Failed to export responses.")),
+	)
+}
+
+// rowWriter writes the rows of an export in one of the supported formats.
+type rowWriter interface {
+	Write(row []string) error
+	Flush() error
+	Close() error
+}
+
+type csvWriter struct {
+	writer   *csv.Writer
+	response *echo.Response
+}
+
+func (w *csvWriter) Write(row []string) error {
+	return w.writer.Write(row)
+}
+
+// Flush sends the rows written so far to the client.
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+
+	w.response.Flush()
+	return nil
+}
+
+func (w *csvWriter) Close() error {
+	return w.Flush()
+}
+
+// xlsxWriter writes rows into a single sheet. Spreadsheets are archives that
+// can only be sent once complete, so rows are buffered until it is closed.
+type xlsxWriter struct {
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	out    io.Writer
+	row    int
+}
+
+func newXlsxWriter(out io.Writer) (*xlsxWriter, error) {
+	file := excelize.NewFile()
+
+	stream, err := file.NewStreamWriter("Sheet1")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &xlsxWriter{file: file, stream: stream, out: out}, nil
+}
+
+func (w *xlsxWriter) Write(row []string) error {
+	w.row++
+
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(row))
+	for i, value := range row {
+		values[i] = value
+	}
+
+	return w.stream.SetRow(cell, values)
+}
+
+func (w *xlsxWriter) Flush() error {
+	return nil
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+
+	_, err := w.file.WriteTo(w.out)
+	return err
+}
@@ -0,0 +1,60 @@
+package responses
+
+import (
+	"backend/db"
+	"backend/spec"
+	"testing"
+)
+
+func TestExportRowFormulas(t *testing.T) {
+	structure, err := spec.Parse(`form {
+	question id="a" type="input" {
+		title "A"
+	}
+	question id="b" type="textarea" {
+		title "B"
+	}
+}`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	columns := spec.Columns(structure)
+
+	tests := []struct {
+		answer string
+		want   string
+	}{
+		{`"=HYPERLINK(\"http://example.com\")"`, `'=HYPERLINK("http://example.com")`},
+		{`"+1"`, "'+1"},
+		{`"-1"`, "'-1"},
+		{`"@SUM(A1)"`, "'@SUM(A1)"},
+		{`"\t=1"`, "'\t=1"},
+		{`"\r=1"`, "'\r=1"},
+		{`"plain"`, "plain"},
+		{`"a=1"`, "a=1"},
+		{`""`, ""},
+	}
+
+	for _, tt := range tests {
+		row := db.ResponseExport{
+			ID:      "response",
+			Status:  db.ResponseStatusCompleted,
+			Answers: []byte(`{"a": ` + tt.answer + `}`),
+		}
+
+		cells := exportRow(db.Form{}, row, columns)
+		if got := cells[len(cells)-2]; got != tt.want {
+			t.Errorf("exportRow() answer = %q, want %q", got, tt.want)
+		}
+		if got := cells[len(cells)-1]; got != "" {
+			t.Errorf("exportRow() unanswered = %q, want it empty", got)
+		}
+	}
+
+	// the handle and email of the respondent are not checked either
+	handle := "=cmd"
+	cells := exportRow(db.Form{}, db.ResponseExport{RespondentHandle: &handle, Answers: []byte(`{}`)}, columns)
+	if cells[7] != "'=cmd" {
+		t.Errorf("exportRow() handle = %q, want %q", cells[7], "'=cmd")
+	}
+}
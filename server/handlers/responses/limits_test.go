@@ -0,0 +1,219 @@
+package responses
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/labstack/echo/v4"
+)
+
+// these tests need a database with the schemas in database/schemas applied,
+// given by FORMS_TEST_DATABASE_URI. they create their own users and forms, so
+// the database does not need to be empty.
+func testDatabase(t *testing.T) (*pgxpool.Pool, context.Context) {
+	t.Helper()
+
+	uri, ok := os.LookupEnv("FORMS_TEST_DATABASE_URI")
+	if !ok {
+		t.Skip("FORMS_TEST_DATABASE_URI is not set")
+	}
+
+	config, err := pgxpool.ParseConfig(uri)
+	if err != nil {
+		t.Fatalf("invalid database uri: %v", err)
+	}
+	config.MaxConns = 32
+
+	ctx := context.Background()
+	conn, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		t.Fatalf("could not connect to database: %v", err)
+	}
+	t.Cleanup(conn.Close)
+
+	utils.LoadValidator()
+	utils.Config.AnonymitySecret = "limits-test"
+
+	return conn, ctx
+}
+
+func TestResponseLimits(t *testing.T) {
+	conn, ctx := testDatabase(t)
+	q := db.New(conn)
+
+	limit := func(n int32) *int32 { return &n }
+
+	tests := []struct {
+		name            string
+		anonymous       bool
+		respondents     int
+		perRespondent   int
+		maxResponses    *int32
+		individualLimit int32
+		want            int
+	}{
+		{"max responses", false, 300, 1, limit(25), 1, 25},
+		{"individual limit", false, 1, 300, nil, 3, 3},
+		{"anonymous individual limit", true, 1, 300, nil, 3, 3},
+		{"both limits", false, 60, 5, limit(40), 2, 40},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := fmt.Sprintf("%d-%d", time.Now().UnixNano(), i)
+
+			owner, err := q.EnsureUser(ctx, db.EnsureUserParams{
+				Handle: "owner-" + run, Email: "owner-" + run + "@example.com", Name: "Owner",
+			})
+			if err != nil {
+				t.Fatalf("failed to create owner: %v", err)
+			}
+
+			form, err := q.CreateFormWithPermissions(ctx, db.CreateFormWithPermissionsParams{
+				OwnerID:         owner.ID,
+				Slug:            "limits-" + run,
+				Title:           "Limits",
+				Structure:       "form {\n\tquestion id=\"a\" type=\"input\" {\n\t\ttitle \"A\"\n\t}\n}",
+				Live:            true,
+				Anonymous:       tt.anonymous,
+				MaxResponses:    tt.maxResponses,
+				IndividualLimit: tt.individualLimit,
+			})
+			if err != nil {
+				t.Fatalf("failed to create form: %v", err)
+			}
+			t.Cleanup(func() {
+				q.DeleteFormByID(ctx, db.DeleteFormByIDParams{ID: form.ID, UserID: owner.ID})
+			})
+
+			_, err = q.GrantPermission(ctx, db.GrantPermissionParams{
+				FormID: form.ID, UserID: owner.ID, Anyone: true, Role: db.PermissionRoleRespond,
+			})
+			if err != nil {
+				t.Fatalf("failed to share form: %v", err)
+			}
+
+			users := make([]db.User, tt.respondents)
+			for r := range users {
+				users[r], err = q.EnsureUser(ctx, db.EnsureUserParams{
+					Handle: fmt.Sprintf("respondent-%s-%d", run, r),
+					Email:  fmt.Sprintf("respondent-%s-%d@example.com", run, r),
+					Name:   "Respondent",
+				})
+				if err != nil {
+					t.Fatalf("failed to create respondent: %v", err)
+				}
+			}
+
+			// every respondent starts and then submits their responses at the
+			// same time as everyone else, so both the early check when starting
+			// and the check under the lock when submitting are raced
+			server := echo.New()
+			start := make(chan struct{})
+			codes := make([][2]int, tt.respondents*tt.perRespondent)
+
+			var wg sync.WaitGroup
+			for n := range codes {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					user := users[n%tt.respondents]
+
+					<-start
+					started := request(server, conn, ctx, q, user, form.ID, "", "", "")
+					codes[n][0] = started.Code
+					if started.Code != http.StatusCreated {
+						return
+					}
+
+					var response struct {
+						ID  string `json:"id"`
+						Key string `json:"key"`
+					}
+					if err := json.Unmarshal(started.Body.Bytes(), &response); err != nil {
+						t.Errorf("failed to decode response: %v", err)
+						return
+					}
+
+					submitted := request(server, conn, ctx, q, user, form.ID, response.ID, response.Key, `{"save": true}`)
+					codes[n][1] = submitted.Code
+				}()
+			}
+			close(start)
+			wg.Wait()
+
+			submitted := 0
+			for _, code := range codes {
+				switch code[0] {
+				case http.StatusCreated:
+				case http.StatusForbidden:
+					continue
+				default:
+					t.Errorf("StartResponse() status = %d, want %d or %d", code[0], http.StatusCreated, http.StatusForbidden)
+					continue
+				}
+
+				switch code[1] {
+				case http.StatusOK:
+					submitted++
+				case http.StatusForbidden:
+				default:
+					t.Errorf("SubmitResponse() status = %d, want %d or %d", code[1], http.StatusOK, http.StatusForbidden)
+				}
+			}
+			if submitted != tt.want {
+				t.Errorf("%d submissions accepted, want %d", submitted, tt.want)
+			}
+
+			completed, err := q.CountResponses(ctx, db.CountResponsesParams{
+				FormID: form.ID, UserID: owner.ID, Status: db.ResponseStatusCompleted,
+			})
+			if err != nil {
+				t.Fatalf("failed to count responses: %v", err)
+			}
+			if completed != int64(tt.want) {
+				t.Errorf("%d completed responses, want %d", completed, tt.want)
+			}
+		})
+	}
+}
+
+// request starts a response to the form as the user when no response is
+// given, and submits the response with the payload otherwise.
+func request(
+	server *echo.Echo, conn *pgxpool.Pool, ctx context.Context, q *db.Queries,
+	user db.User, formID, responseID, key, payload string,
+) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if key != "" {
+		req.Header.Set(ResponseKeyHeader, key)
+	}
+	rec := httptest.NewRecorder()
+
+	c := server.NewContext(req, rec)
+	c.Set("user", user)
+	if responseID == "" {
+		c.SetParamNames("formId")
+		c.SetParamValues(formID)
+		StartResponse(dbcontext.New(c, conn, &ctx, q))
+	} else {
+		c.SetParamNames("formId", "responseId")
+		c.SetParamValues(formID, responseID)
+		SubmitResponse(dbcontext.New(c, conn, &ctx, q))
+	}
+
+	return rec
+}
@@ -3,6 +3,7 @@ package responses
 import (
 	"backend/context"
 	"backend/db"
+	"backend/spec"
 	"backend/utility"
 	"errors"
 	"net/http"
@@ -112,15 +113,35 @@ func ListResponses(c echo.Context) error {
 
 func StartResponse(c echo.Context) error {
 	cc := c.(*dbcontext.Context)
-	user := c.Get("user").(db.User)
+	userID, _ := respondent(c)
 
 	formID := c.Param("formId")
 
+	var key *string
+	if userID != nil {
+		// only used if the form turns out to be anonymous
+		generated, err := utils.NewResponseKey()
+		if err != nil {
+			log.Error("failed to generate response key", "error", err)
+			return c.JSON(
+				http.StatusInternalServerError,
+				utils.FromError(utils.ErrorInternal, errors.New("Failed to start response.")),
+			)
+		}
+		key = &generated
+	} else {
+		// guests already hold a key to their responses, their guest token
+		guest := c.Get("guest").(string)
+		key = &guest
+	}
+
 	response, err := cc.Query.StartResponse(
 		*cc.DbCtx,
 		db.StartResponseParams{
 			FormID: formID,
-			UserID: user.ID,
+			UserID: userID,
+			Token:  participantToken(c, formID),
+			Key:    key,
 		},
 	)
 
@@ -140,12 +161,21 @@ func StartResponse(c echo.Context) error {
 		)
 	}
 
+	// the key is the only way back to a response to an anonymous form, and is
+	// not stored, so it can only be handed out now
+	if userID != nil && response.Respondent == nil {
+		return c.JSON(http.StatusCreated, struct {
+			db.Response
+			Key string `json:"key"`
+		}{response, *key})
+	}
+
 	return c.JSON(http.StatusCreated, response)
 }
 
 func GetResponse(c echo.Context) error {
 	cc := c.(*dbcontext.Context)
-	user := c.Get("user").(db.User)
+	userID, key := respondent(c)
 
 	formID := c.Param("formId")
 	responseID := c.Param("responseId")
@@ -155,7 +185,8 @@ func GetResponse(c echo.Context) error {
 		db.GetResponseParams{
 			ID:     responseID,
 			FormID: formID,
-			UserID: user.ID,
+			UserID: userID,
+			Key:    key,
 		},
 	)
 
@@ -175,12 +206,31 @@ func GetResponse(c echo.Context) error {
 		)
 	}
 
-	return c.JSON(http.StatusOK, response)
+	// the score is only visible once released, and so is the feedback
+	if response.Score == nil {
+		return c.JSON(http.StatusOK, response)
+	}
+
+	_, structure, err := getStructure(cc, formID, responseID, userID, key)
+	if err != nil {
+		return structureError(c, err, "Failed to fetch response.")
+	}
+
+	answers, err := getAnswers(cc, formID, responseID, userID, key)
+	if err != nil {
+		return structureError(c, err, "Failed to fetch response.")
+	}
+
+	score := structure.Score(answers)
+	return c.JSON(http.StatusOK, struct {
+		db.Response
+		Feedback spec.Score `json:"feedback"`
+	}{response, score})
 }
 
 func GetAnswers(c echo.Context) error {
 	cc := c.(*dbcontext.Context)
-	user := c.Get("user").(db.User)
+	userID, key := respondent(c)
 
 	formID := c.Param("formId")
 	responseID := c.Param("responseId")
@@ -190,7 +240,8 @@ func GetAnswers(c echo.Context) error {
 		db.GetAnswersParams{
 			ID:     responseID,
 			FormID: formID,
-			UserID: user.ID,
+			UserID: userID,
+			Key:    key,
 		},
 	)
 
@@ -215,13 +266,50 @@ func GetAnswers(c echo.Context) error {
 	})
 }
 
-func SaveAnswer(c echo.Context) error {
+func GetAnswerHistory(c echo.Context) error {
 	cc := c.(*dbcontext.Context)
 	user := c.Get("user").(db.User)
 
 	formID := c.Param("formId")
 	responseID := c.Param("responseId")
 
+	history, err := cc.Query.ListAnswerHistory(
+		*cc.DbCtx,
+		db.ListAnswerHistoryParams{
+			ID:     responseID,
+			FormID: formID,
+			UserID: user.ID,
+		},
+	)
+
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+
+		log.Error("failed to fetch answer history", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to fetch answer history.")),
+		)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": utils.EmptyArrayIfNull(history),
+	})
+}
+
+func SaveAnswer(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	userID, key := respondent(c)
+
+	formID := c.Param("formId")
+	responseID := c.Param("responseId")
+
 	type Payload struct {
 		Question string `json:"question" validate:"required"`
 		Value    string `json:"value" validate:"required"`
@@ -250,39 +338,61 @@ func SaveAnswer(c echo.Context) error {
 		)
 	}
 
-	answer, err := cc.Query.SaveAnswer(
-		*cc.DbCtx,
-		db.SaveAnswerParams{
-			ID:       responseID,
-			FormID:   formID,
-			UserID:   user.ID,
-			Question: payload.Question,
-			Value:    payload.Value,
-		},
+	answers, _, errs, err := saveAnswers(
+		cc, formID, responseID, userID, key,
+		map[string]string{payload.Question: payload.Value}, nil,
 	)
+	if err != nil {
+		return saveError(c, err, "Failed to save answer.")
+	}
+	if len(errs) > 0 {
+		return invalidAnswers(c, errs)
+	}
+
+	return c.JSON(http.StatusOK, answers[0])
+}
 
+func GetVisibleQuestions(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	userID, key := respondent(c)
+
+	formID := c.Param("formId")
+	responseID := c.Param("responseId")
+
+	_, structure, err := getStructure(cc, formID, responseID, userID, key)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
-			return c.JSON(
-				http.StatusForbidden,
-				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
-			)
-		}
+		return structureError(c, err, "Failed to fetch visible questions.")
+	}
 
-		log.Error("failed to save answer", "error", err)
-		return c.JSON(
-			http.StatusInternalServerError,
-			utils.FromError(utils.ErrorInternal, errors.New("Failed to save answer.")),
-		)
+	answers, err := getAnswers(cc, formID, responseID, userID, key)
+	if err != nil {
+		return structureError(c, err, "Failed to fetch visible questions.")
 	}
 
-	return c.JSON(http.StatusOK, answer)
+	visible := structure.Visible(answers)
+
+	sections := []string{}
+	questions := []string{}
+	for _, element := range structure.Elements {
+		if section, ok := element.(*spec.Section); ok && visible[section.ID] {
+			sections = append(sections, section.ID)
+		}
+	}
+	for _, question := range structure.Questions() {
+		if visible[question.ID] {
+			questions = append(questions, question.ID)
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"sections":  sections,
+		"questions": questions,
+	})
 }
 
 func SubmitResponse(c echo.Context) error {
 	cc := c.(*dbcontext.Context)
-	user := c.Get("user").(db.User)
+	userID, key := respondent(c)
 
 	formID := c.Param("formId")
 	responseID := c.Param("responseId")
@@ -314,35 +424,122 @@ func SubmitResponse(c echo.Context) error {
 		)
 	}
 
-	response, err := cc.Query.SubmitResponse(
+	form, structure, err := getStructure(cc, formID, responseID, userID, key)
+	if err != nil {
+		return structureError(c, err, "Failed to submit response.")
+	}
+
+	tx, err := cc.DbConn.Begin(*cc.DbCtx)
+	if err != nil {
+		log.Error("failed to begin transaction", "error", err)
+		return c.JSON(
+			http.StatusInternalServerError,
+			utils.FromError(utils.ErrorInternal, errors.New("Failed to submit response.")),
+		)
+	}
+	defer tx.Rollback(*cc.DbCtx)
+
+	txc := *cc
+	txc.Query = cc.Query.WithTx(tx)
+
+	// locks the response until the transaction ends, so that answers saved
+	// meanwhile cannot slip past the checks below
+	_, err = txc.Query.AdvanceRevision(
 		*cc.DbCtx,
-		db.SubmitResponseParams{
+		db.AdvanceRevisionParams{
 			ID:     responseID,
 			FormID: formID,
-			UserID: user.ID,
-			Save:   payload.Save,
+			UserID: userID,
+			Key:    key,
 		},
 	)
+	if err != nil {
+		return submitError(c, err)
+	}
 
+	answers, err := getAnswers(&txc, formID, responseID, userID, key)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && (pgErr.Hint == "form-closed" || pgErr.Hint == "forbidden") {
-			return c.JSON(
-				http.StatusForbidden,
-				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
-			)
+		return structureError(c, err, "Failed to submit response.")
+	}
+
+	if missing := structure.Missing(answers); len(missing) > 0 {
+		return incompleteResponse(c, missing)
+	}
+
+	// answers to questions that ended up hidden are not kept, unless the
+	// submission is turned away, in which case they are rolled back with it
+	visible := structure.Visible(answers)
+	hidden := []string{}
+	for question := range answers {
+		if !visible[question] {
+			hidden = append(hidden, question)
 		}
+	}
 
-		log.Error("failed to submit response", "error", err)
-		return c.JSON(
-			http.StatusInternalServerError,
-			utils.FromError(utils.ErrorInternal, errors.New("Failed to submit response.")),
+	if len(hidden) > 0 {
+		err = txc.Query.RemoveAnswers(
+			*cc.DbCtx,
+			db.RemoveAnswersParams{
+				ID:        responseID,
+				FormID:    formID,
+				UserID:    userID,
+				Key:       key,
+				Questions: hidden,
+			},
 		)
+		if err != nil {
+			return submitError(c, err)
+		}
+	}
+
+	var score *int32
+	if form.Quiz {
+		points := int32(structure.Score(answers).Points)
+		score = &points
+	}
+
+	response, err := txc.Query.SubmitResponse(
+		*cc.DbCtx,
+		db.SubmitResponseParams{
+			ID:     responseID,
+			FormID: formID,
+			UserID: userID,
+			Key:    key,
+			Token:  participantToken(c, formID),
+			Save:   payload.Save,
+			Score:  score,
+		},
+	)
+	if err != nil {
+		return submitError(c, err)
+	}
+
+	if err := tx.Commit(*cc.DbCtx); err != nil {
+		return submitError(c, err)
 	}
 
 	return c.JSON(http.StatusOK, response)
 }
 
+// submitError sends the response for an error returned while submitting a
+// response.
+func submitError(c echo.Context, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Hint == "form-closed" || pgErr.Hint == "forbidden" ||
+		pgErr.Hint == "response-locked") {
+		return c.JSON(
+			http.StatusForbidden,
+			utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+		)
+	}
+
+	log.Error("failed to submit response", "error", err)
+	return c.JSON(
+		http.StatusInternalServerError,
+		utils.FromError(utils.ErrorInternal, errors.New("Failed to submit response.")),
+	)
+}
+
 func ListSavedResponses(c echo.Context) error {
 	cc := c.(*dbcontext.Context)
 	user := c.Get("user").(db.User)
@@ -0,0 +1,177 @@
+package responses
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/utility"
+	"errors"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+func SaveAnswers(c echo.Context) error {
+	cc := c.(*dbcontext.Context)
+	userID, key := respondent(c)
+
+	formID := c.Param("formId")
+	responseID := c.Param("responseId")
+
+	type Payload struct {
+		Answers  map[string]string `json:"answers" validate:"required,min=1"`
+		Revision *int32            `json:"revision" validate:"omitempty,gte=0"`
+	}
+
+	payload := Payload{}
+
+	if err := c.Bind(&payload); err != nil {
+		return c.JSON(
+			http.StatusBadRequest,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New("Failed to parse request payload."),
+			),
+		)
+	}
+
+	if err := utils.Validate.Struct(payload); err != nil {
+		message := utils.FormatValidationErrors(err)
+		return c.JSON(
+			http.StatusUnprocessableEntity,
+			utils.FromError(
+				utils.ErrorBadRequest,
+				errors.New(message),
+			),
+		)
+	}
+
+	answers, revision, errs, err := saveAnswers(
+		cc, formID, responseID, userID, key, payload.Answers, payload.Revision,
+	)
+	if err != nil {
+		return saveError(c, err, "Failed to save answers.")
+	}
+	if len(errs) > 0 {
+		return invalidAnswers(c, errs)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data":     utils.EmptyArrayIfNull(answers),
+		"revision": revision,
+	})
+}
+
+// saveAnswers validates answers to a response and saves them in a single
+// transaction, so that either all of them are saved or none are. Questions
+// must be visible for the answers they are saved alongside. If a revision is
+// given, the answers are only saved if the response has not been saved since.
+// Answers that fail validation are returned instead of an error.
+func saveAnswers(
+	cc *dbcontext.Context, formID, responseID string, userID, key *string,
+	values map[string]string, revision *int32,
+) ([]db.Answer, int32, []utils.FieldError, error) {
+	_, structure, err := getStructure(cc, formID, responseID, userID, key)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	tx, err := cc.DbConn.Begin(*cc.DbCtx)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer tx.Rollback(*cc.DbCtx)
+
+	txc := *cc
+	txc.Query = cc.Query.WithTx(tx)
+
+	// locks the response until the transaction ends
+	next, err := txc.Query.AdvanceRevision(
+		*cc.DbCtx,
+		db.AdvanceRevisionParams{
+			ID:       responseID,
+			FormID:   formID,
+			UserID:   userID,
+			Key:      key,
+			Revision: revision,
+		},
+	)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	answers, err := getAnswers(&txc, formID, responseID, userID, key)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	for question, value := range values {
+		answers[question] = []byte(value)
+	}
+
+	questions := slices.Sorted(maps.Keys(values))
+	visible := structure.Visible(answers)
+
+	errs := []utils.FieldError{}
+	for _, question := range questions {
+		if err := validateAnswer(structure, visible, question, values[question]); err != nil {
+			errs = append(errs, *err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, 0, errs, nil
+	}
+
+	saved := make([]db.Answer, 0, len(questions))
+	for _, question := range questions {
+		answer, err := txc.Query.SaveAnswer(
+			*cc.DbCtx,
+			db.SaveAnswerParams{
+				ID:       responseID,
+				FormID:   formID,
+				UserID:   userID,
+				Key:      key,
+				Question: question,
+				Value:    values[question],
+			},
+		)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		saved = append(saved, answer)
+	}
+
+	if err := tx.Commit(*cc.DbCtx); err != nil {
+		return nil, 0, nil, err
+	}
+
+	return saved, next, nil, nil
+}
+
+// saveError sends the response for an error returned by saveAnswers.
+func saveError(c echo.Context, err error, message string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Hint {
+		case "forbidden", "response-locked":
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		case "stale-revision":
+			return c.JSON(
+				http.StatusConflict,
+				utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+			)
+		}
+	}
+
+	log.Error("failed to save answers", "error", err)
+	return c.JSON(
+		http.StatusInternalServerError,
+		utils.FromError(utils.ErrorInternal, errors.New(message)),
+	)
+}
@@ -0,0 +1,179 @@
+package responses
+
+import (
+	"backend/context"
+	"backend/db"
+	"backend/spec"
+	"backend/utility"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/labstack/echo/v4"
+)
+
+// ResponseKeyHeader carries the key respondents to anonymous forms are given
+// when they start a response, since it is not linked to them.
+const ResponseKeyHeader = "X-Response-Key"
+
+// respondent identifies who is responding to a form: the logged in user, or
+// for guests, no user. The key proves ownership of responses that are not
+// linked to a user, and is the response key sent with the request, if any,
+// or the guest token.
+func respondent(c echo.Context) (userID *string, key *string) {
+	if user, ok := c.Get("user").(db.User); ok {
+		userID = &user.ID
+	}
+
+	if header := c.Request().Header.Get(ResponseKeyHeader); header != "" {
+		key = &header
+	} else if guest, ok := c.Get("guest").(string); ok {
+		key = &guest
+	}
+
+	return userID, key
+}
+
+// participantToken identifies the respondent within a single form, without
+// revealing who they are, to enforce its limits when responses are not linked
+// to them.
+func participantToken(c echo.Context, formID string) string {
+	if user, ok := c.Get("user").(db.User); ok {
+		return utils.ParticipantToken(formID, user.ID)
+	}
+	return utils.ParticipantToken(formID, "guest:"+c.Get("guest").(string))
+}
+
+// getStructure fetches the form that a response belongs to, along with its
+// parsed structure, as seen by its respondent.
+func getStructure(
+	cc *dbcontext.Context, formID, responseID string, userID, key *string,
+) (db.Form, *spec.Form, error) {
+	form, err := cc.Query.GetFormForResponse(
+		*cc.DbCtx,
+		db.GetFormForResponseParams{
+			ID:     responseID,
+			FormID: formID,
+			UserID: userID,
+			Key:    key,
+		},
+	)
+	if err != nil {
+		return form, nil, err
+	}
+
+	structure, err := spec.Parse(form.Structure)
+	if err != nil {
+		return form, nil, fmt.Errorf("failed to parse structure of form %s: %w", form.ID, err)
+	}
+
+	return form, structure, nil
+}
+
+// getAnswers fetches the answers of a response, keyed by the question they
+// are for.
+func getAnswers(
+	cc *dbcontext.Context, formID, responseID string, userID, key *string,
+) (map[string][]byte, error) {
+	answers, err := cc.Query.GetAnswers(
+		*cc.DbCtx,
+		db.GetAnswersParams{
+			ID:     responseID,
+			FormID: formID,
+			UserID: userID,
+			Key:    key,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(answers))
+	for _, answer := range answers {
+		values[answer.Question] = answer.Value
+	}
+
+	return values, nil
+}
+
+// structureError sends the response for an error returned by getStructure or
+// getAnswers.
+func structureError(c echo.Context, err error, message string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Hint == "forbidden" {
+		return c.JSON(
+			http.StatusForbidden,
+			utils.FromError(utils.HttpErrorCode(pgErr.Hint), errors.New(pgErr.Message)),
+		)
+	}
+
+	log.Error("failed to fetch response details", "error", err)
+	return c.JSON(
+		http.StatusInternalServerError,
+		utils.FromError(utils.ErrorInternal, errors.New(message)),
+	)
+}
+
+// validateAnswer checks an answer against the question it is for, returning
+// nil if the answer is acceptable. Questions that are not visible do not
+// accept answers.
+func validateAnswer(
+	structure *spec.Form, visible map[string]bool, questionID string, value string,
+) *utils.FieldError {
+	question := structure.Question(questionID)
+	if question == nil {
+		return &utils.FieldError{
+			Field:   questionID,
+			Message: fmt.Sprintf("%s is not a question in this form", questionID),
+		}
+	}
+
+	if !visible[questionID] {
+		return &utils.FieldError{
+			Field:   questionID,
+			Message: fmt.Sprintf("%s is not shown for the current answers", questionID),
+		}
+	}
+
+	if err := question.Validate([]byte(value)); err != nil {
+		return &utils.FieldError{Field: questionID, Message: err.Error()}
+	}
+
+	return nil
+}
+
+func invalidAnswers(c echo.Context, errs []utils.FieldError) error {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Message
+	}
+
+	return c.JSON(
+		http.StatusUnprocessableEntity,
+		utils.FromErrors(
+			utils.ErrorInvalidAnswer,
+			errors.New("Failed to process payload - "+strings.Join(messages, ", ")+"."),
+			errs,
+		),
+	)
+}
+
+func incompleteResponse(c echo.Context, missing []string) error {
+	errs := make([]utils.FieldError, len(missing))
+	for i, id := range missing {
+		errs[i] = utils.FieldError{Field: id, Message: id + " is required"}
+	}
+
+	return c.JSON(
+		http.StatusUnprocessableEntity,
+		utils.FromErrors(
+			utils.ErrorIncompleteResponse,
+			errors.New("Failed to submit response - required questions have not been answered: "+
+				strings.Join(missing, ", ")+"."),
+			errs,
+		),
+	)
+}
@@ -21,6 +21,11 @@ func main() {
 	utils.LoadValidator()
 	utils.SetupLogger()
 
+	if utils.Config.AnonymitySecret == "" {
+		log.Error("FORMS_ANONYMITY_SECRET must be set")
+		os.Exit(1)
+	}
+
 	ctx := context.Background()
 	conn, err := pgxpool.New(ctx, utils.Config.DatabaseUri)
 	if err != nil {
@@ -35,6 +40,11 @@ func main() {
 	defer conn.Close()
 	q := db.New(conn)
 
+	if err := q.SyncAdmins(ctx, utils.Config.Admins); err != nil {
+		log.Error("could not update site administrators", "error", err.Error())
+		os.Exit(1)
+	}
+
 	server := echo.New()
 	server.HideBanner = true
 	server.HidePort = true
@@ -2,10 +2,14 @@ package middleware
 
 import (
 	"backend/context"
+	"backend/db"
 	"backend/utility"
 	"errors"
 	"net/http"
 
+	"github.com/charmbracelet/log"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/labstack/echo/v4"
 )
 
@@ -27,17 +31,150 @@ func Auth(next echo.HandlerFunc) echo.HandlerFunc {
 			)
 		}
 
+		stored, err := touchSession(c, session)
+		if revoked(err) {
+			return c.JSON(
+				http.StatusUnauthorized,
+				utils.FromError(utils.ErrorUnauthorized, errors.New("Session has expired or been revoked.")),
+			)
+		} else if err != nil {
+			return sessionError(c, err)
+		}
+
 		cc := c.(*dbcontext.Context)
 		user, err := cc.Query.GetUserById(*cc.DbCtx, session.ID)
-		if err != nil {
+		if revoked(err) {
 			return c.JSON(
 				http.StatusUnauthorized,
 				utils.FromError(utils.ErrorUnauthorized, errors.New("Invalid user.")),
 			)
+		} else if err != nil {
+			return sessionError(c, err)
+		}
+
+		if user.Disabled {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.ErrorForbidden, errors.New("Your account has been disabled.")),
+			)
 		}
 
 		c.Set("user", user)
+		c.Set("session", stored.ID)
 
 		return next(c)
 	}
 }
+
+// Admin is like Auth, but only lets site administrators through.
+func Admin(next echo.HandlerFunc) echo.HandlerFunc {
+	return Auth(func(c echo.Context) error {
+		user := c.Get("user").(db.User)
+		if !user.Admin {
+			return c.JSON(
+				http.StatusForbidden,
+				utils.FromError(utils.ErrorForbidden, errors.New("Only administrators can do this.")),
+			)
+		}
+
+		return next(c)
+	})
+}
+
+// OptionalAuth sets the user if logged in, like Auth, but lets guests through
+// as well. Guests are identified by a token kept in a cookie instead, which is
+// handed out on their first request.
+func OptionalAuth(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, session, ok, err := sessionUser(c)
+		if err != nil {
+			return sessionError(c, err)
+		}
+		if ok {
+			c.Set("user", user)
+			c.Set("session", session.ID)
+			return next(c)
+		}
+
+		cookie, err := c.Cookie(utils.GuestCookieName)
+		if err == nil && cookie.Value != "" {
+			c.Set("guest", cookie.Value)
+			return next(c)
+		}
+
+		token, err := utils.NewGuestToken()
+		if err != nil {
+			log.Error("failed to generate guest token", "error", err)
+			return c.JSON(
+				http.StatusInternalServerError,
+				utils.FromError(utils.ErrorInternal, errors.New("Failed to identify guest.")),
+			)
+		}
+
+		c.SetCookie(utils.GuestCookie(token))
+		c.Set("guest", token)
+
+		return next(c)
+	}
+}
+
+// sessionUser finds the logged in user, if the session is valid. An error is
+// only returned if the session could not be checked.
+func sessionUser(c echo.Context) (db.User, db.Session, bool, error) {
+	cookie, err := c.Cookie(utils.SessionCookieName)
+	if err != nil {
+		return db.User{}, db.Session{}, false, nil
+	}
+
+	session, err := utils.ValidateSession(cookie.Value)
+	if err != nil {
+		return db.User{}, db.Session{}, false, nil
+	}
+
+	stored, err := touchSession(c, session)
+	if revoked(err) {
+		return db.User{}, db.Session{}, false, nil
+	} else if err != nil {
+		return db.User{}, db.Session{}, false, err
+	}
+
+	cc := c.(*dbcontext.Context)
+	user, err := cc.Query.GetUserById(*cc.DbCtx, session.ID)
+	if revoked(err) || err == nil && user.Disabled {
+		return db.User{}, db.Session{}, false, nil
+	} else if err != nil {
+		return db.User{}, db.Session{}, false, err
+	}
+
+	return user, stored, true, nil
+}
+
+// revoked reports whether an error from checking a session means that the
+// session, or its user, is gone, rather than that the database failed.
+func revoked(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.Is(err, pgx.ErrNoRows) || errors.As(err, &pgErr) && pgErr.Hint == "not-found"
+}
+
+func sessionError(c echo.Context, err error) error {
+	log.Error("failed to check session", "error", err)
+	return c.JSON(
+		http.StatusInternalServerError,
+		utils.FromError(utils.ErrorInternal, errors.New("Failed to check session.")),
+	)
+}
+
+// touchSession checks that the session in the cookie has not been revoked, and
+// notes where it was last used from.
+func touchSession(c echo.Context, session *utils.Session) (db.Session, error) {
+	cc := c.(*dbcontext.Context)
+	ip := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	return cc.Query.TouchSession(*cc.DbCtx, db.TouchSessionParams{
+		ID:        session.Session,
+		UserID:    session.ID,
+		Ip:        &ip,
+		UserAgent: &userAgent,
+	})
+}
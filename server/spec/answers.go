@@ -0,0 +1,259 @@
+package spec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const dateLayout = "2006-01-02"
+
+// decodeAnswer decodes an answer, keeping numbers in their original textual
+// form so they can be compared against option values.
+func decodeAnswer(raw []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if decoder.More() {
+		return nil, errors.New("unexpected data after value")
+	}
+
+	return value, nil
+}
+
+// IsEmpty reports whether an answer counts as unanswered: null, an empty
+// string, or an empty list or object.
+func IsEmpty(raw []byte) bool {
+	value, err := decodeAnswer(raw)
+	if err != nil {
+		return false
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// Validate checks an answer, encoded as JSON, against the type of the question
+// and its validations. Empty answers are always accepted here, since whether a
+// question must be answered is only checked on submission.
+func (q *Question) Validate(raw []byte) error {
+	value, err := decodeAnswer(raw)
+	if err != nil {
+		return fmt.Errorf("%s must be valid JSON", q.ID)
+	}
+
+	if IsEmpty(raw) {
+		return nil
+	}
+
+	switch q.Type {
+	case TypeInput, TypeTextarea:
+		return q.validateText(value)
+	case TypeRadio, TypeSelect:
+		option, ok := optionValue(value)
+		if !ok || q.Option(option) == nil {
+			return fmt.Errorf("%s must be one of: %s", q.ID, q.optionList())
+		}
+	case TypeCheckbox:
+		return q.validateCheckbox(value)
+	case TypeMatrix:
+		return q.validateMatrix(value)
+	case TypeLikert:
+		step, err := integer(value)
+		if err != nil || step < 1 || step > q.Steps {
+			return fmt.Errorf("%s must be a whole number between 1 and %d", q.ID, q.Steps)
+		}
+	case TypeDate:
+		date, ok := value.(string)
+		if _, err := time.Parse(dateLayout, date); !ok || err != nil {
+			return fmt.Errorf("%s must be a date in the YYYY-MM-DD format", q.ID)
+		}
+	case TypeFile:
+		return q.validateFiles(value)
+	case TypeSectionHeader:
+		return fmt.Errorf("%s does not accept answers", q.ID)
+	}
+
+	return nil
+}
+
+func (q *Question) validateText(value interface{}) error {
+	text, ok := value.(string)
+	if !ok {
+		return fmt.Errorf("%s must be a string", q.ID)
+	}
+
+	v := q.Validations
+	chars := int64(utf8.RuneCountInString(text))
+	words := int64(len(strings.Fields(text)))
+
+	if v.MinChars != nil && chars < *v.MinChars {
+		return fmt.Errorf("%s must be at least %d characters long", q.ID, *v.MinChars)
+	}
+	if v.MaxChars != nil && chars > *v.MaxChars {
+		return fmt.Errorf("%s must not exceed %d characters", q.ID, *v.MaxChars)
+	}
+	if v.MinWords != nil && words < *v.MinWords {
+		return fmt.Errorf("%s must be at least %d words long", q.ID, *v.MinWords)
+	}
+	if v.MaxWords != nil && words > *v.MaxWords {
+		return fmt.Errorf("%s must not exceed %d words", q.ID, *v.MaxWords)
+	}
+
+	// patterns have already been checked when the structure was parsed
+	if v.Regex != nil && !regexp.MustCompile(*v.Regex).MatchString(text) {
+		return fmt.Errorf("%s must match the pattern %s", q.ID, *v.Regex)
+	}
+
+	return nil
+}
+
+func (q *Question) validateCheckbox(value interface{}) error {
+	values, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be a list of options", q.ID)
+	}
+
+	seen := map[string]bool{}
+	for _, v := range values {
+		option, ok := optionValue(v)
+		if !ok || q.Option(option) == nil {
+			return fmt.Errorf("%s must only contain the options: %s", q.ID, q.optionList())
+		}
+
+		if seen[option] {
+			return fmt.Errorf("%s must not contain duplicate options", q.ID)
+		}
+		seen[option] = true
+	}
+
+	return nil
+}
+
+func (q *Question) validateMatrix(value interface{}) error {
+	rows, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s must map categories to options", q.ID)
+	}
+
+	for category, v := range rows {
+		if q.Category(category) == nil {
+			return fmt.Errorf("%s has an unknown category %s", q.ID, category)
+		}
+
+		option, ok := optionValue(v)
+		if !ok || q.Option(option) == nil {
+			return fmt.Errorf("%s must map %s to one of: %s", q.ID, category, q.optionList())
+		}
+	}
+
+	return nil
+}
+
+func (q *Question) validateFiles(value interface{}) error {
+	files, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("%s must be a list of files", q.ID)
+	}
+
+	for _, file := range files {
+		if _, ok := file.(string); !ok {
+			return fmt.Errorf("%s must be a list of files", q.ID)
+		}
+	}
+
+	if q.MaxFiles != nil && int64(len(files)) > *q.MaxFiles {
+		return fmt.Errorf("%s must not have more than %d files", q.ID, *q.MaxFiles)
+	}
+
+	return nil
+}
+
+func (q *Question) optionList() string {
+	values := make([]string, len(q.Options))
+	for i, option := range q.Options {
+		values[i] = option.Value
+	}
+	return strings.Join(values, ", ")
+}
+
+// optionValue formats a decoded string or number the same way option values
+// are formatted when parsing a structure.
+func optionValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	}
+	return "", false
+}
+
+func integer(value interface{}) (int64, error) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, errors.New("not a number")
+	}
+	return number.Int64()
+}
+
+// Answered reports whether an answer fully answers the question. For matrix
+// questions, every category must have an option picked.
+func (q *Question) Answered(raw []byte) bool {
+	if raw == nil || IsEmpty(raw) {
+		return false
+	}
+
+	if q.Type == TypeMatrix {
+		value, err := decodeAnswer(raw)
+		if err != nil {
+			return false
+		}
+
+		rows, _ := value.(map[string]interface{})
+		for _, category := range q.Categories {
+			if _, ok := rows[category.Value]; !ok {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// Missing returns the ids of the required questions that are visible but have
+// not been answered, in the order they appear in the form. Answers are keyed by
+// the id of the question they are for.
+func (f *Form) Missing(answers map[string][]byte) []string {
+	visible := f.Visible(answers)
+
+	missing := []string{}
+	for _, question := range f.Questions() {
+		if !visible[question.ID] {
+			continue
+		}
+
+		if question.Required && !question.Answered(answers[question.ID]) {
+			missing = append(missing, question.ID)
+		}
+	}
+	return missing
+}
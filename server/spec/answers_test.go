@@ -0,0 +1,201 @@
+package spec
+
+import "testing"
+
+// mustParse parses a structure that is known to be valid.
+func mustParse(t *testing.T, source string) *Form {
+	t.Helper()
+
+	form, err := Parse(source)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	return form
+}
+
+func TestIsEmpty(t *testing.T) {
+	tests := map[string]bool{
+		`null`:  true,
+		`""`:    true,
+		`"  "`:  true,
+		`[]`:    true,
+		`{}`:    true,
+		`"a"`:   false,
+		`0`:     false,
+		`false`: false,
+		`[""]`:  false,
+		`{`:     false,
+	}
+
+	for raw, want := range tests {
+		if got := IsEmpty([]byte(raw)); got != want {
+			t.Errorf("IsEmpty(%s) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	form := mustParse(t, `form {
+	question id="name" type="input" {
+		title "Name"
+		validations {
+			regex "^[a-z ]+$"
+			min-chars 2
+			max-chars 10
+			min-words 1
+			max-words 2
+		}
+	}
+	question id="pick" type="radio" {
+		title "Pick"
+		option value="a"
+		option value=2
+	}
+	question id="many" type="checkbox" {
+		title "Many"
+		option value="a"
+		option value="b"
+	}
+	question id="grid" type="matrix" {
+		title "Grid"
+		category value="x"
+		category value="y"
+		option value="lo"
+		option value="hi"
+	}
+	question id="scale" type="likert" {
+		title "Scale"
+		steps 3
+	}
+	question id="when" type="date" {
+		title "When"
+	}
+	question id="upload" type="file" {
+		title "Upload"
+		max-files 1
+	}
+	question id="header" type="section-header" {
+		title "Header"
+	}
+}`)
+
+	tests := []struct {
+		question string
+		value    string
+		want     string
+	}{
+		{"name", `"ada"`, ""},
+		{"name", `""`, ""},
+		{"name", `null`, ""},
+		{"name", `nope`, "name must be valid JSON"},
+		{"name", `1`, "name must be a string"},
+		{"name", `"a"`, "name must be at least 2 characters long"},
+		{"name", `"abcdefghijk"`, "name must not exceed 10 characters"},
+		{"name", `"a b c"`, "name must not exceed 2 words"},
+		{"name", `"Ada"`, "name must match the pattern ^[a-z ]+$"},
+		{"pick", `"a"`, ""},
+		{"pick", `2`, ""},
+		{"pick", `"2"`, ""},
+		{"pick", `"c"`, "pick must be one of: a, 2"},
+		{"pick", `["a"]`, "pick must be one of: a, 2"},
+		{"many", `["a", "b"]`, ""},
+		{"many", `"a"`, "many must be a list of options"},
+		{"many", `["a", "c"]`, "many must only contain the options: a, b"},
+		{"many", `["a", "a"]`, "many must not contain duplicate options"},
+		{"grid", `{"x": "lo"}`, ""},
+		{"grid", `{"z": "lo"}`, "grid has an unknown category z"},
+		{"grid", `{"x": "mid"}`, "grid must map x to one of: lo, hi"},
+		{"grid", `["lo"]`, "grid must map categories to options"},
+		{"scale", `3`, ""},
+		{"scale", `0`, "scale must be a whole number between 1 and 3"},
+		{"scale", `4`, "scale must be a whole number between 1 and 3"},
+		{"scale", `1.5`, "scale must be a whole number between 1 and 3"},
+		{"when", `"2025-02-28"`, ""},
+		{"when", `"2025-02-30"`, "when must be a date in the YYYY-MM-DD format"},
+		{"when", `"28/02/2025"`, "when must be a date in the YYYY-MM-DD format"},
+		{"upload", `["file-1"]`, ""},
+		{"upload", `["file-1", "file-2"]`, "upload must not have more than 1 files"},
+		{"upload", `[1]`, "upload must be a list of files"},
+		{"header", `"x"`, "header does not accept answers"},
+	}
+
+	for _, tt := range tests {
+		err := form.Question(tt.question).Validate([]byte(tt.value))
+
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("%s.Validate(%s) = %q, want %q", tt.question, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMissing(t *testing.T) {
+	form := mustParse(t, `form {
+	question id="a" type="input" required {
+		title "A"
+	}
+	question id="b" type="input" {
+		title "B"
+	}
+	question id="grid" type="matrix" required {
+		title "Grid"
+		category value="x"
+		category value="y"
+		option value=1
+	}
+	section id="more" {
+		visible-if {
+			answered question="b"
+		}
+		question id="c" type="date" required {
+			title "C"
+		}
+	}
+}`)
+
+	tests := []struct {
+		name    string
+		answers map[string]string
+		want    []string
+	}{
+		{"nothing answered", map[string]string{}, []string{"a", "grid"}},
+		{"empty answer", map[string]string{"a": `" "`, "grid": `{"x": 1, "y": 1}`}, []string{"a"}},
+		{"partial matrix", map[string]string{"a": `"a"`, "grid": `{"x": 1}`}, []string{"grid"}},
+		{
+			"shown by condition",
+			map[string]string{"a": `"a"`, "b": `"b"`, "grid": `{"x": 1, "y": 1}`},
+			[]string{"c"},
+		},
+		{
+			"complete",
+			map[string]string{"a": `"a"`, "b": `"b"`, "grid": `{"x": 1, "y": 1}`, "c": `"2025-01-01"`},
+			[]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := form.Missing(answers(tt.answers))
+			if len(got) != len(tt.want) {
+				t.Fatalf("Missing() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Missing() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// answers converts answers written as strings to the form they are stored in.
+func answers(values map[string]string) map[string][]byte {
+	result := make(map[string][]byte, len(values))
+	for question, value := range values {
+		result[question] = []byte(value)
+	}
+	return result
+}
@@ -0,0 +1,660 @@
+package spec
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSteps is the largest scale a likert question may have.
+const maxSteps = 100
+
+// builder turns a parsed KDL document into a Form, collecting every problem it
+// comes across instead of stopping at the first one.
+type builder struct {
+	errors Errors
+	ids    map[string]bool
+	form   *Form
+}
+
+func (b *builder) errorf(line, col int, format string, args ...interface{}) {
+	b.errors = append(b.errors, Error{
+		Line: line, Column: col, Message: fmt.Sprintf(format, args...),
+	})
+}
+
+func (b *builder) document(nodes []*node) *Form {
+	var root *node
+	for _, n := range nodes {
+		if n.Name != "form" {
+			b.errorf(n.Line, n.Column, "unexpected node '%s', expected 'form'", n.Name)
+			continue
+		}
+
+		if root != nil {
+			b.errorf(n.Line, n.Column, "only one 'form' node is allowed")
+			continue
+		}
+
+		root = n
+	}
+
+	if root == nil {
+		if len(b.errors) == 0 {
+			b.errorf(1, 1, "missing 'form' node")
+		}
+		return nil
+	}
+
+	return b.root(root)
+}
+
+func (b *builder) root(n *node) *Form {
+	b.ids = map[string]bool{}
+	b.form = &Form{Version: 1, Elements: []Element{}, questions: map[string]*Question{}}
+	b.noEntries(n)
+
+	seen := map[string]bool{}
+	for _, child := range n.Children {
+		switch child.Name {
+		case "version":
+			if !b.once(seen, child) {
+				continue
+			}
+
+			version, ok := b.integerArg(child)
+			if ok && version != 1 {
+				b.errorf(child.Line, child.Column, "unsupported version %d", version)
+			}
+			b.form.Version = version
+		case "title":
+			if b.once(seen, child) {
+				b.form.Title, _ = b.stringArg(child)
+			}
+		case "description":
+			if b.once(seen, child) {
+				b.form.Description, _ = b.stringArg(child)
+			}
+		case "section":
+			if section := b.section(child); section != nil {
+				b.form.Elements = append(b.form.Elements, section)
+			}
+		case "question":
+			if question := b.question(child, ""); question != nil {
+				b.form.Elements = append(b.form.Elements, question)
+			}
+		default:
+			b.unknown(child, "form")
+		}
+	}
+
+	return b.form
+}
+
+func (b *builder) section(n *node) *Section {
+	b.noArgs(n)
+	b.allowProps(n, "id")
+
+	id, ok := b.id(n)
+	if !ok {
+		return nil
+	}
+
+	s := &Section{ID: id, Questions: []*Question{}}
+
+	// the condition is read first, so that it cannot refer to the questions in
+	// the section itself
+	seen := map[string]bool{}
+	for _, child := range n.Children {
+		if child.Name == "visible-if" && b.once(seen, child) {
+			s.VisibleIf = b.visibleIf(child)
+		}
+	}
+
+	for _, child := range n.Children {
+		switch child.Name {
+		case "visible-if":
+			// already read above
+		case "title":
+			if b.once(seen, child) {
+				s.Title, _ = b.stringArg(child)
+			}
+		case "description":
+			if b.once(seen, child) {
+				s.Description, _ = b.stringArg(child)
+			}
+		case "question":
+			if question := b.question(child, s.ID); question != nil {
+				s.Questions = append(s.Questions, question)
+			}
+		default:
+			b.unknown(child, "section")
+		}
+	}
+
+	return s
+}
+
+func (b *builder) question(n *node, section string) *Question {
+	b.allowProps(n, "id", "type", "required")
+
+	id, ok := b.id(n)
+	if !ok {
+		return nil
+	}
+
+	q := &Question{ID: id, Section: section, Line: n.Line, Column: n.Column}
+
+	typeOk := false
+	if v, present := n.Props["type"]; !present {
+		b.errorf(n.Line, n.Column, "question '%s' is missing a type", id)
+	} else if t, isString := v.Raw.(string); !isString || !slices.Contains(questionTypes, QuestionType(t)) {
+		b.errorf(v.Line, v.Column, "unknown question type %s", formatValue(v))
+	} else {
+		q.Type = QuestionType(t)
+		typeOk = true
+	}
+
+	if v, present := n.Props["required"]; present {
+		required, isBool := v.Raw.(bool)
+		if !isBool {
+			b.errorf(v.Line, v.Column, "required must be true or false")
+		}
+		q.Required = required
+	}
+
+	for _, arg := range n.Args {
+		if arg.Raw == "required" {
+			q.Required = true
+			continue
+		}
+		b.errorf(arg.Line, arg.Column, "unexpected argument %s", formatValue(arg))
+	}
+
+	var correct *node
+	seen := map[string]bool{}
+	for _, child := range n.Children {
+		switch child.Name {
+		case "title":
+			if b.once(seen, child) {
+				q.Title, _ = b.stringArg(child)
+			}
+		case "description":
+			if b.once(seen, child) {
+				q.Description, _ = b.stringArg(child)
+			}
+		case "placeholder":
+			if b.applicable(child, q, typeOk, TypeInput, TypeTextarea, TypeSelect) && b.once(seen, child) {
+				q.Placeholder, _ = b.stringArg(child)
+			}
+		case "option":
+			if b.applicable(child, q, typeOk, TypeRadio, TypeCheckbox, TypeSelect, TypeMatrix) {
+				if option, ok := b.option(child, q.Options); ok {
+					q.Options = append(q.Options, option)
+				}
+			}
+		case "category":
+			if b.applicable(child, q, typeOk, TypeMatrix) {
+				if category, ok := b.option(child, q.Categories); ok {
+					q.Categories = append(q.Categories, category)
+				}
+			}
+		case "visible-if":
+			if b.once(seen, child) {
+				q.VisibleIf = b.visibleIf(child)
+			}
+		case "validations":
+			if b.applicable(child, q, typeOk, TypeInput, TypeTextarea) && b.once(seen, child) {
+				q.Validations = b.validations(child)
+			}
+		case "icon":
+			if b.applicable(child, q, typeOk, TypeLikert) && b.once(seen, child) {
+				q.Icon, _ = b.stringArg(child)
+			}
+		case "steps":
+			if b.applicable(child, q, typeOk, TypeLikert) && b.once(seen, child) {
+				steps, ok := b.integerArg(child)
+				if ok && steps < 2 {
+					b.errorf(child.Line, child.Column, "steps must be at least 2")
+				}
+				if ok && steps > maxSteps {
+					b.errorf(child.Line, child.Column, "steps must be at most %d", maxSteps)
+				}
+				q.Steps = steps
+			}
+		case "min-label":
+			if b.applicable(child, q, typeOk, TypeLikert) && b.once(seen, child) {
+				q.MinLabel, _ = b.stringArg(child)
+			}
+		case "max-label":
+			if b.applicable(child, q, typeOk, TypeLikert) && b.once(seen, child) {
+				q.MaxLabel, _ = b.stringArg(child)
+			}
+		case "max-file-size":
+			if b.applicable(child, q, typeOk, TypeFile) && b.once(seen, child) {
+				if size, ok := b.fileSize(child); ok {
+					q.MaxFileSize = &size
+				}
+			}
+		case "max-files":
+			if b.applicable(child, q, typeOk, TypeFile) && b.once(seen, child) {
+				count, ok := b.integerArg(child)
+				if ok && count < 1 {
+					b.errorf(child.Line, child.Column, "max-files must be at least 1")
+				}
+				if ok {
+					q.MaxFiles = &count
+				}
+			}
+		case "allowed-types":
+			if b.applicable(child, q, typeOk, TypeFile) && b.once(seen, child) {
+				q.AllowedTypes = b.stringArgs(child)
+			}
+		case "correct":
+			if b.applicable(child, q, typeOk, scoredTypes...) && b.once(seen, child) {
+				correct = child
+				q.Correct = b.scalarArgs(child)
+			}
+		case "points":
+			if b.applicable(child, q, typeOk, scoredTypes...) && b.once(seen, child) {
+				points, ok := b.integerArg(child)
+				if ok && points < 0 {
+					b.errorf(child.Line, child.Column, "points must not be negative")
+				}
+				q.Points = points
+			}
+		default:
+			b.unknown(child, "question")
+		}
+	}
+
+	if !seen["title"] {
+		b.errorf(n.Line, n.Column, "question '%s' is missing a title", id)
+	}
+
+	// points are kept in structures shown to respondents, whose answer keys
+	// have been removed, so they are not an error on their own
+	if correct != nil {
+		b.correct(correct, q)
+		if !seen["points"] {
+			q.Points = 1
+		}
+	}
+
+	if !typeOk {
+		return q
+	}
+
+	switch q.Type {
+	case TypeRadio, TypeCheckbox, TypeSelect:
+		if len(q.Options) == 0 {
+			b.errorf(n.Line, n.Column, "%s question '%s' must have at least one option", q.Type, id)
+		}
+	case TypeMatrix:
+		if len(q.Options) == 0 || len(q.Categories) == 0 {
+			b.errorf(n.Line, n.Column, "matrix question '%s' must have at least one category and option", id)
+		}
+	case TypeLikert:
+		if q.Steps == 0 {
+			q.Steps = 5
+		}
+	case TypeSectionHeader:
+		if q.Required {
+			b.errorf(n.Line, n.Column, "section-header '%s' cannot be required", id)
+		}
+	}
+
+	b.form.questions[q.ID] = q
+	return q
+}
+
+// correct checks the answer key of a question, once all of its options are
+// known.
+func (b *builder) correct(n *node, q *Question) {
+	seen := map[string]bool{}
+	for _, arg := range n.Args {
+		value, ok := scalar(arg)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case q.HasOptions() && q.Option(value) == nil:
+			b.errorf(arg.Line, arg.Column, "%s is not an option of '%s'", formatValue(arg), q.ID)
+		case q.Type == TypeDate:
+			if _, err := time.Parse(dateLayout, value); err != nil {
+				b.errorf(arg.Line, arg.Column, "correct dates must be in the YYYY-MM-DD format")
+			}
+		}
+
+		if seen[value] {
+			b.errorf(arg.Line, arg.Column, "%s is listed more than once", formatValue(arg))
+		}
+		seen[value] = true
+	}
+}
+
+func (b *builder) option(n *node, existing []Option) (Option, bool) {
+	b.noArgs(n)
+	b.noChildren(n)
+	b.allowProps(n, "value", "label")
+
+	v, present := n.Props["value"]
+	if !present {
+		b.errorf(n.Line, n.Column, "%s is missing a value", n.Name)
+		return Option{}, false
+	}
+
+	value, ok := scalar(v)
+	if !ok {
+		b.errorf(v.Line, v.Column, "%s value must be a string or a number", n.Name)
+		return Option{}, false
+	}
+
+	for _, option := range existing {
+		if option.Value == value {
+			b.errorf(v.Line, v.Column, "duplicate %s value '%s'", n.Name, value)
+			return Option{}, false
+		}
+	}
+
+	label := value
+	if l, present := n.Props["label"]; present {
+		if s, isString := l.Raw.(string); isString {
+			label = s
+		} else {
+			b.errorf(l.Line, l.Column, "%s label must be a string", n.Name)
+		}
+	}
+
+	return Option{Value: value, Label: label}, true
+}
+
+func (b *builder) validations(n *node) Validations {
+	var v Validations
+	b.noEntries(n)
+
+	seen := map[string]bool{}
+	for _, child := range n.Children {
+		if !slices.Contains([]string{"regex", "min-chars", "max-chars", "min-words", "max-words"}, child.Name) {
+			b.unknown(child, "validations")
+			continue
+		}
+		if !b.once(seen, child) {
+			continue
+		}
+
+		if child.Name == "regex" {
+			pattern, ok := b.stringArg(child)
+			if !ok {
+				continue
+			}
+
+			if _, err := regexp.Compile(pattern); err != nil {
+				b.errorf(child.Line, child.Column, "invalid regex: %s", err.Error())
+				continue
+			}
+			v.Regex = &pattern
+			continue
+		}
+
+		limit, ok := b.integerArg(child)
+		if !ok {
+			continue
+		}
+		if limit < 0 {
+			b.errorf(child.Line, child.Column, "%s must not be negative", child.Name)
+			continue
+		}
+
+		switch child.Name {
+		case "min-chars":
+			v.MinChars = &limit
+		case "max-chars":
+			v.MaxChars = &limit
+		case "min-words":
+			v.MinWords = &limit
+		case "max-words":
+			v.MaxWords = &limit
+		}
+	}
+
+	if v.MinChars != nil && v.MaxChars != nil && *v.MinChars > *v.MaxChars {
+		b.errorf(n.Line, n.Column, "min-chars must not exceed max-chars")
+	}
+	if v.MinWords != nil && v.MaxWords != nil && *v.MinWords > *v.MaxWords {
+		b.errorf(n.Line, n.Column, "min-words must not exceed max-words")
+	}
+
+	return v
+}
+
+// fileSize reads a size in megabytes, with an optional unit argument.
+func (b *builder) fileSize(n *node) (float64, bool) {
+	b.noChildren(n)
+	b.allowProps(n)
+
+	if len(n.Args) < 1 || len(n.Args) > 2 {
+		b.errorf(n.Line, n.Column, "%s expects a size and an optional unit", n.Name)
+		return 0, false
+	}
+
+	size, ok := number(n.Args[0])
+	if !ok || size <= 0 {
+		b.errorf(n.Args[0].Line, n.Args[0].Column, "%s must be a positive number", n.Name)
+		return 0, false
+	}
+
+	if len(n.Args) == 2 {
+		unit, _ := n.Args[1].Raw.(string)
+		switch strings.ToLower(unit) {
+		case "kb":
+			size /= 1024
+		case "mb":
+		case "gb":
+			size *= 1024
+		default:
+			b.errorf(n.Args[1].Line, n.Args[1].Column, "unknown unit %s, expected kb, mb or gb", formatValue(n.Args[1]))
+			return 0, false
+		}
+	}
+
+	return size, true
+}
+
+// id reads the `id` property of a node and makes sure it is unique across
+// the whole form.
+func (b *builder) id(n *node) (string, bool) {
+	v, present := n.Props["id"]
+	if !present {
+		b.errorf(n.Line, n.Column, "%s is missing an id", n.Name)
+		return "", false
+	}
+
+	id, ok := v.Raw.(string)
+	if !ok || id == "" {
+		b.errorf(v.Line, v.Column, "%s id must be a non-empty string", n.Name)
+		return "", false
+	}
+
+	if b.ids[id] {
+		b.errorf(v.Line, v.Column, "duplicate id '%s'", id)
+		return "", false
+	}
+	b.ids[id] = true
+
+	return id, true
+}
+
+// applicable reports whether the node may be used on the question, reporting
+// an error if it cannot. nothing is reported if the question type is invalid.
+func (b *builder) applicable(n *node, q *Question, typeOk bool, types ...QuestionType) bool {
+	if !typeOk {
+		return false
+	}
+
+	if !slices.Contains(types, q.Type) {
+		b.errorf(n.Line, n.Column, "'%s' is not allowed on %s questions", n.Name, q.Type)
+		return false
+	}
+
+	return true
+}
+
+func (b *builder) once(seen map[string]bool, n *node) bool {
+	if seen[n.Name] {
+		b.errorf(n.Line, n.Column, "duplicate '%s'", n.Name)
+		return false
+	}
+
+	seen[n.Name] = true
+	return true
+}
+
+func (b *builder) unknown(n *node, parent string) {
+	b.errorf(n.Line, n.Column, "unexpected node '%s' in %s", n.Name, parent)
+}
+
+func (b *builder) stringArg(n *node) (string, bool) {
+	if !b.singleArg(n) {
+		return "", false
+	}
+
+	s, ok := n.Args[0].Raw.(string)
+	if !ok {
+		b.errorf(n.Args[0].Line, n.Args[0].Column, "%s must be a string", n.Name)
+	}
+
+	return s, ok
+}
+
+func (b *builder) stringArgs(n *node) []string {
+	b.noChildren(n)
+	b.allowProps(n)
+
+	if len(n.Args) == 0 {
+		b.errorf(n.Line, n.Column, "%s expects at least one value", n.Name)
+	}
+
+	var values []string
+	for _, arg := range n.Args {
+		s, ok := arg.Raw.(string)
+		if !ok {
+			b.errorf(arg.Line, arg.Column, "%s values must be strings", n.Name)
+			continue
+		}
+		values = append(values, s)
+	}
+
+	return values
+}
+
+// scalarArgs reads the string or number arguments of a node. Arguments that
+// are neither are reported and left out.
+func (b *builder) scalarArgs(n *node) []string {
+	b.noChildren(n)
+	b.allowProps(n)
+
+	if len(n.Args) == 0 {
+		b.errorf(n.Line, n.Column, "%s expects at least one value", n.Name)
+	}
+
+	var values []string
+	for _, arg := range n.Args {
+		s, ok := scalar(arg)
+		if !ok {
+			b.errorf(arg.Line, arg.Column, "%s values must be strings or numbers", n.Name)
+			continue
+		}
+		values = append(values, s)
+	}
+
+	return values
+}
+
+func (b *builder) integerArg(n *node) (int64, bool) {
+	if !b.singleArg(n) {
+		return 0, false
+	}
+
+	i, ok := n.Args[0].Raw.(int64)
+	if !ok {
+		b.errorf(n.Args[0].Line, n.Args[0].Column, "%s must be an integer", n.Name)
+	}
+
+	return i, ok
+}
+
+func (b *builder) singleArg(n *node) bool {
+	b.noChildren(n)
+	b.allowProps(n)
+
+	if len(n.Args) != 1 {
+		b.errorf(n.Line, n.Column, "%s expects exactly one value", n.Name)
+		return false
+	}
+
+	return true
+}
+
+func (b *builder) noEntries(n *node) {
+	b.noArgs(n)
+	b.allowProps(n)
+}
+
+func (b *builder) noArgs(n *node) {
+	for _, arg := range n.Args {
+		b.errorf(arg.Line, arg.Column, "unexpected argument %s", formatValue(arg))
+	}
+}
+
+func (b *builder) noChildren(n *node) {
+	if len(n.Children) > 0 {
+		child := n.Children[0]
+		b.errorf(child.Line, child.Column, "%s cannot have children", n.Name)
+	}
+}
+
+func (b *builder) allowProps(n *node, allowed ...string) {
+	for key, v := range n.Props {
+		if !slices.Contains(allowed, key) {
+			b.errorf(v.Line, v.Column, "unexpected property '%s'", key)
+		}
+	}
+}
+
+func number(v value) (float64, bool) {
+	switch raw := v.Raw.(type) {
+	case int64:
+		return float64(raw), true
+	case float64:
+		return raw, true
+	}
+	return 0, false
+}
+
+// scalar formats a string or numeric value as a string.
+func scalar(v value) (string, bool) {
+	switch raw := v.Raw.(type) {
+	case string:
+		return raw, true
+	case int64:
+		return strconv.FormatInt(raw, 10), true
+	case float64:
+		return strconv.FormatFloat(raw, 'f', -1, 64), true
+	}
+	return "", false
+}
+
+func formatValue(v value) string {
+	switch raw := v.Raw.(type) {
+	case string:
+		return strconv.Quote(raw)
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(v.Raw)
+}
@@ -0,0 +1,105 @@
+package spec
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Column is a column in a table of answers, such as an export of responses.
+// Matrix questions take up a column for each of their categories.
+type Column struct {
+	Question string
+	Category string // only for matrix questions
+}
+
+// Header is the name of the column, made up of the question id and, for
+// matrix questions, the category value.
+func (c Column) Header() string {
+	if c.Category == "" {
+		return c.Question
+	}
+	return c.Question + "." + c.Category
+}
+
+// Columns lists the columns needed for the answers to a form, in the order the
+// questions appear. When given several versions of a form, newest first, the
+// columns of the newest version come first, followed by those of questions
+// that only exist in older versions.
+func Columns(versions ...*Form) []Column {
+	columns := []Column{}
+	seen := map[Column]bool{}
+
+	for _, f := range versions {
+		for _, q := range f.Questions() {
+			if q.Type == TypeSectionHeader {
+				continue
+			}
+
+			categories := []string{""}
+			if q.Type == TypeMatrix {
+				categories = categories[:0]
+				for _, category := range q.Categories {
+					categories = append(categories, category.Value)
+				}
+			}
+
+			for _, category := range categories {
+				column := Column{Question: q.ID, Category: category}
+				if !seen[column] {
+					seen[column] = true
+					columns = append(columns, column)
+				}
+			}
+		}
+	}
+
+	return columns
+}
+
+// Cell formats the answer for the column as text. Lists, such as the options
+// picked for a checkbox question, are joined with semicolons, and unanswered
+// questions are left empty.
+func (c Column) Cell(answers map[string][]byte) string {
+	raw, ok := answers[c.Question]
+	if !ok {
+		return ""
+	}
+
+	value, err := decodeAnswer(raw)
+	if err != nil {
+		return ""
+	}
+
+	if c.Category != "" {
+		rows, _ := value.(map[string]interface{})
+		value = rows[c.Category]
+	}
+
+	return cell(value)
+}
+
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case []interface{}:
+		values := make([]string, len(v))
+		for i, item := range v {
+			values[i] = cell(item)
+		}
+		return strings.Join(values, "; ")
+	}
+
+	// objects outside of matrix columns are not expected, but are kept intact
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
@@ -0,0 +1,69 @@
+package spec
+
+import "testing"
+
+func TestColumns(t *testing.T) {
+	older := mustParse(t, `form {
+	question id="name" type="input" {
+		title "Name"
+	}
+	question id="removed" type="date" {
+		title "Removed"
+	}
+}`)
+	newer := mustParse(t, `form {
+	question id="intro" type="section-header" {
+		title "Intro"
+	}
+	question id="grid" type="matrix" {
+		title "Grid"
+		category value="x"
+		category value="y"
+		option value=1
+	}
+	question id="name" type="input" {
+		title "Name"
+	}
+}`)
+
+	columns := Columns(newer, older)
+
+	want := []string{"grid.x", "grid.y", "name", "removed"}
+	if len(columns) != len(want) {
+		t.Fatalf("Columns() = %v, want %v", columns, want)
+	}
+	for i := range want {
+		if columns[i].Header() != want[i] {
+			t.Errorf("Columns()[%d] = %s, want %s", i, columns[i].Header(), want[i])
+		}
+	}
+}
+
+func TestCell(t *testing.T) {
+	tests := []struct {
+		column Column
+		value  string
+		want   string
+	}{
+		{Column{Question: "q"}, `"text"`, "text"},
+		{Column{Question: "q"}, `12.5`, "12.5"},
+		{Column{Question: "q"}, `true`, "true"},
+		{Column{Question: "q"}, `null`, ""},
+		{Column{Question: "q"}, `["a", 2]`, "a; 2"},
+		{Column{Question: "q"}, `{"a": 1}`, `{"a":1}`},
+		{Column{Question: "q", Category: "x"}, `{"x": "lo", "y": "hi"}`, "lo"},
+		{Column{Question: "q", Category: "z"}, `{"x": "lo"}`, ""},
+		{Column{Question: "q"}, `not json`, ""},
+	}
+
+	for _, tt := range tests {
+		got := tt.column.Cell(map[string][]byte{"q": []byte(tt.value)})
+		if got != tt.want {
+			t.Errorf("%s.Cell(%s) = %q, want %q", tt.column.Header(), tt.value, got, tt.want)
+		}
+	}
+
+	if got := (Column{Question: "other"}).Cell(map[string][]byte{}); got != "" {
+		t.Errorf("Cell() of an unanswered question = %q, want empty", got)
+	}
+}
@@ -0,0 +1,285 @@
+package spec
+
+import (
+	"slices"
+	"strconv"
+)
+
+type ConditionOp string
+
+const (
+	OpAll         ConditionOp = "all"
+	OpAny         ConditionOp = "any"
+	OpNot         ConditionOp = "not"
+	OpAnswered    ConditionOp = "answered"
+	OpEquals      ConditionOp = "equals"
+	OpContains    ConditionOp = "contains"
+	OpLessThan    ConditionOp = "less-than"
+	OpGreaterThan ConditionOp = "greater-than"
+)
+
+// Condition decides whether a section or question is shown, based on the
+// answers given to earlier questions. The all, any and not operators combine
+// the conditions nested inside them, while the rest compare the answer to a
+// single question.
+type Condition struct {
+	Op         ConditionOp `json:"op"`
+	Question   string      `json:"question,omitempty"`
+	Category   string      `json:"category,omitempty"` // only for matrix questions
+	Value      string      `json:"value,omitempty"`
+	Conditions []Condition `json:"conditions,omitempty"`
+}
+
+// holds evaluates the condition against the answers to visible questions. A
+// nil condition always holds.
+func (c *Condition) holds(f *Form, answers map[string][]byte) bool {
+	if c == nil {
+		return true
+	}
+
+	switch c.Op {
+	case OpAll:
+		for i := range c.Conditions {
+			if !c.Conditions[i].holds(f, answers) {
+				return false
+			}
+		}
+		return true
+	case OpAny:
+		for i := range c.Conditions {
+			if c.Conditions[i].holds(f, answers) {
+				return true
+			}
+		}
+		return false
+	case OpNot:
+		return !c.Conditions[0].holds(f, answers)
+	}
+
+	question := f.Question(c.Question)
+	raw, ok := answers[c.Question]
+	if question == nil || !ok || IsEmpty(raw) {
+		return false
+	}
+
+	if c.Op == OpAnswered {
+		return question.Answered(raw)
+	}
+
+	value, err := decodeAnswer(raw)
+	if err != nil {
+		return false
+	}
+
+	if c.Category != "" {
+		rows, _ := value.(map[string]interface{})
+		value = rows[c.Category]
+	}
+
+	switch c.Op {
+	case OpEquals:
+		answer, ok := optionValue(value)
+		return ok && answer == c.Value
+	case OpContains:
+		values, _ := value.([]interface{})
+		for _, v := range values {
+			if answer, ok := optionValue(v); ok && answer == c.Value {
+				return true
+			}
+		}
+		return false
+	case OpLessThan, OpGreaterThan:
+		answer, ok := optionValue(value)
+		if !ok {
+			return false
+		}
+
+		x, err := strconv.ParseFloat(answer, 64)
+		if err != nil {
+			return false
+		}
+		y, _ := strconv.ParseFloat(c.Value, 64)
+
+		if c.Op == OpLessThan {
+			return x < y
+		}
+		return x > y
+	}
+
+	return false
+}
+
+// Visible works out which sections and questions are shown for the given
+// answers, returning a set of their ids. Answers to hidden questions are
+// ignored while doing so, which means that anything depending on a hidden
+// question is hidden as well.
+func (f *Form) Visible(answers map[string][]byte) map[string]bool {
+	visible := map[string]bool{}
+	shown := map[string][]byte{}
+
+	check := func(q *Question, parentVisible bool) {
+		if !parentVisible || !q.VisibleIf.holds(f, shown) {
+			return
+		}
+
+		visible[q.ID] = true
+		if answer, ok := answers[q.ID]; ok {
+			shown[q.ID] = answer
+		}
+	}
+
+	for _, element := range f.Elements {
+		switch e := element.(type) {
+		case *Section:
+			sectionVisible := e.VisibleIf.holds(f, shown)
+			if sectionVisible {
+				visible[e.ID] = true
+			}
+
+			for _, q := range e.Questions {
+				check(q, sectionVisible)
+			}
+		case *Question:
+			check(e, true)
+		}
+	}
+
+	return visible
+}
+
+var conditionOps = []ConditionOp{
+	OpAll, OpAny, OpNot, OpAnswered, OpEquals, OpContains, OpLessThan, OpGreaterThan,
+}
+
+// visibleIf reads a `visible-if` block, whose conditions must all hold.
+func (b *builder) visibleIf(n *node) *Condition {
+	b.noEntries(n)
+
+	if len(n.Children) == 0 {
+		b.errorf(n.Line, n.Column, "visible-if must contain at least one condition")
+		return nil
+	}
+
+	c := &Condition{Op: OpAll}
+	for _, child := range n.Children {
+		if condition, ok := b.condition(child); ok {
+			c.Conditions = append(c.Conditions, condition)
+		}
+	}
+
+	if len(c.Conditions) == 1 {
+		return &c.Conditions[0]
+	}
+	return c
+}
+
+func (b *builder) condition(n *node) (Condition, bool) {
+	op := ConditionOp(n.Name)
+	if !slices.Contains(conditionOps, op) {
+		b.unknown(n, "visible-if")
+		return Condition{}, false
+	}
+
+	c := Condition{Op: op}
+
+	switch op {
+	case OpAll, OpAny, OpNot:
+		b.noEntries(n)
+
+		for _, child := range n.Children {
+			if condition, ok := b.condition(child); ok {
+				c.Conditions = append(c.Conditions, condition)
+			}
+		}
+
+		if len(n.Children) == 0 || op == OpNot && len(n.Children) != 1 {
+			count := "at least one condition"
+			if op == OpNot {
+				count = "exactly one condition"
+			}
+
+			b.errorf(n.Line, n.Column, "%s must contain %s", op, count)
+			return Condition{}, false
+		}
+
+		return c, len(c.Conditions) == len(n.Children)
+	}
+
+	b.noArgs(n)
+	b.noChildren(n)
+	if op == OpAnswered {
+		b.allowProps(n, "question")
+	} else {
+		b.allowProps(n, "question", "category", "value")
+	}
+
+	v, present := n.Props["question"]
+	if !present {
+		b.errorf(n.Line, n.Column, "%s is missing a question", op)
+		return Condition{}, false
+	}
+
+	id, _ := v.Raw.(string)
+	question := b.form.questions[id]
+	if question == nil {
+		b.errorf(v.Line, v.Column, "%s can only refer to questions that come before it", formatValue(v))
+		return Condition{}, false
+	}
+	if question.Type == TypeSectionHeader {
+		b.errorf(v.Line, v.Column, "%s cannot be answered", formatValue(v))
+		return Condition{}, false
+	}
+	c.Question = id
+
+	if op == OpAnswered {
+		return c, true
+	}
+
+	if category, present := n.Props["category"]; present {
+		c.Category, _ = category.Raw.(string)
+		if question.Type != TypeMatrix || question.Category(c.Category) == nil {
+			b.errorf(category.Line, category.Column, "%s is not a category of '%s'", formatValue(category), id)
+			return Condition{}, false
+		}
+	} else if question.Type == TypeMatrix {
+		b.errorf(n.Line, n.Column, "%s on matrix question '%s' needs a category", op, id)
+		return Condition{}, false
+	}
+
+	value, present := n.Props["value"]
+	if !present {
+		b.errorf(n.Line, n.Column, "%s is missing a value", op)
+		return Condition{}, false
+	}
+
+	var ok bool
+	if c.Value, ok = scalar(value); !ok {
+		b.errorf(value.Line, value.Column, "value must be a string or a number")
+		return Condition{}, false
+	}
+
+	switch op {
+	case OpContains:
+		if question.Type != TypeCheckbox {
+			b.errorf(n.Line, n.Column, "contains can only be used with checkbox questions")
+			return Condition{}, false
+		}
+	case OpEquals:
+		if question.Type == TypeCheckbox || question.Type == TypeFile {
+			b.errorf(n.Line, n.Column, "equals cannot be used with %s questions", question.Type)
+			return Condition{}, false
+		}
+	case OpLessThan, OpGreaterThan:
+		if _, err := strconv.ParseFloat(c.Value, 64); err != nil {
+			b.errorf(value.Line, value.Column, "%s needs a numeric value", op)
+			return Condition{}, false
+		}
+	}
+
+	if question.HasOptions() && op != OpLessThan && op != OpGreaterThan && question.Option(c.Value) == nil {
+		b.errorf(value.Line, value.Column, "%s is not an option of '%s'", formatValue(value), id)
+		return Condition{}, false
+	}
+
+	return c, true
+}
@@ -0,0 +1,170 @@
+package spec
+
+import (
+	"errors"
+	"testing"
+)
+
+const conditional = `form {
+	question id="reasons" type="checkbox" {
+		title "Why did you drop the course?"
+		option value="time"
+		option value="other"
+	}
+	question id="other" type="textarea" {
+		title "What else made you drop it?"
+		visible-if {
+			contains question="reasons" value="other"
+		}
+	}
+	question id="detail" type="input" {
+		title "Tell us more."
+		visible-if {
+			answered question="other"
+		}
+	}
+	question id="age" type="input" {
+		title "Age"
+	}
+	section id="adults" {
+		visible-if {
+			any {
+				greater-than question="age" value=17
+				contains question="reasons" value="time"
+			}
+			not {
+				less-than question="age" value=0
+			}
+		}
+		question id="job" type="input" {
+			title "Job"
+		}
+	}
+	question id="grid" type="matrix" {
+		title "Grid"
+		category value="x"
+		option value="lo"
+		option value="hi"
+	}
+	question id="high" type="input" {
+		title "Why high?"
+		visible-if {
+			equals question="grid" category="x" value="hi"
+		}
+	}
+}`
+
+func TestVisible(t *testing.T) {
+	form := mustParse(t, conditional)
+
+	tests := []struct {
+		name    string
+		answers map[string]string
+		want    []string
+	}{
+		{
+			name:    "nothing answered",
+			answers: map[string]string{},
+			want:    []string{"reasons", "age", "grid"},
+		},
+		{
+			name:    "contains",
+			answers: map[string]string{"reasons": `["other"]`},
+			want:    []string{"reasons", "other", "age", "grid"},
+		},
+		{
+			name:    "chained",
+			answers: map[string]string{"reasons": `["other"]`, "other": `"busy"`},
+			want:    []string{"reasons", "other", "detail", "age", "grid"},
+		},
+		{
+			// answers to hidden questions do not make others visible
+			name:    "hidden dependency",
+			answers: map[string]string{"reasons": `["time"]`, "other": `"busy"`, "detail": `"x"`},
+			want:    []string{"reasons", "age", "adults", "job", "grid"},
+		},
+		{
+			name:    "greater than",
+			answers: map[string]string{"age": `"18"`},
+			want:    []string{"reasons", "age", "adults", "job", "grid"},
+		},
+		{
+			name:    "not greater than",
+			answers: map[string]string{"age": `"17"`},
+			want:    []string{"reasons", "age", "grid"},
+		},
+		{
+			name:    "not a number",
+			answers: map[string]string{"age": `"old"`},
+			want:    []string{"reasons", "age", "grid"},
+		},
+		{
+			name:    "negated",
+			answers: map[string]string{"age": `"-1"`, "reasons": `["time"]`},
+			want:    []string{"reasons", "age", "grid"},
+		},
+		{
+			name:    "matrix category",
+			answers: map[string]string{"grid": `{"x": "hi"}`},
+			want:    []string{"reasons", "age", "grid", "high"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			visible := form.Visible(answers(tt.answers))
+
+			if len(visible) != len(tt.want) {
+				t.Fatalf("Visible() = %v, want %v", visible, tt.want)
+			}
+			for _, id := range tt.want {
+				if !visible[id] {
+					t.Fatalf("Visible() = %v, want %v", visible, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	prefix := "form {\n\tquestion id=\"a\" type=\"radio\" {\n\t\ttitle \"a\"\n\t\toption value=1\n\t}\n" +
+		"\tquestion id=\"m\" type=\"matrix\" {\n\t\ttitle \"m\"\n\t\tcategory value=\"x\"\n\t\toption value=1\n\t}\n" +
+		"\tquestion id=\"q\" type=\"input\" {\n\t\ttitle \"q\"\n\t\tvisible-if {\n"
+	suffix := "\n\t\t}\n\t}\n}"
+
+	// conditions start on line 14, after three tabs
+	tests := []struct {
+		name      string
+		condition string
+		want      Error
+	}{
+		{"unknown", `unless question="a"`, Error{14, 4, "unexpected node 'unless' in visible-if"}},
+		{"missing question", `answered`, Error{14, 4, "answered is missing a question"}},
+		{"later question", `answered question="q"`, Error{14, 22, "\"q\" can only refer to questions that come before it"}},
+		{"missing value", `equals question="a"`, Error{14, 4, "equals is missing a value"}},
+		{"not an option", `equals question="a" value=2`, Error{14, 30, "2 is not an option of 'a'"}},
+		{"contains on radio", `contains question="a" value=1`, Error{14, 4, "contains can only be used with checkbox questions"}},
+		{"not numeric", `less-than question="a" value="x"`, Error{14, 33, "less-than needs a numeric value"}},
+		{"matrix without category", `equals question="m" value=1`, Error{14, 4, "equals on matrix question 'm' needs a category"}},
+		{"unknown category", `equals question="m" category="y" value=1`, Error{14, 33, "\"y\" is not a category of 'm'"}},
+		{"empty not", `not`, Error{14, 4, "not must contain exactly one condition"}},
+		{"empty any", `any`, Error{14, 4, "any must contain at least one condition"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(prefix + "\t\t\t" + tt.condition + suffix)
+
+			var errs Errors
+			if !errors.As(err, &errs) || len(errs) != 1 || errs[0] != tt.want {
+				t.Errorf("Parse() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	_, err := Parse("form {\n\tsection id=\"s\" {\n\t\tvisible-if\n\t}\n}")
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0] != (Error{3, 3, "visible-if must contain at least one condition"}) {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
@@ -0,0 +1,176 @@
+package spec
+
+import "reflect"
+
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "added"
+	ChangeRemoved  ChangeKind = "removed"
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is a single difference between two versions of a form. Element is the
+// id of the section or question that changed, and is empty for changes to the
+// form itself. Field is the part of the element that changed, named after its
+// JSON representation, and is empty when the whole element was added or
+// removed.
+type Change struct {
+	Kind    ChangeKind  `json:"kind"`
+	Element string      `json:"element,omitempty"`
+	Field   string      `json:"field,omitempty"`
+	Old     interface{} `json:"old"`
+	New     interface{} `json:"new"`
+}
+
+type differ struct {
+	changes []Change
+}
+
+// Diff lists the changes that turn one version of a form into another, in
+// terms of its sections and questions rather than the text of the structure.
+// Changes are listed in the order their elements appear in the newer version,
+// followed by the elements that were removed.
+func Diff(from, to *Form) []Change {
+	d := differ{changes: []Change{}}
+
+	d.field("", "title", from.Title, to.Title)
+	d.field("", "description", from.Description, to.Description)
+
+	fromSections := sections(from)
+	toSections := sections(to)
+
+	for _, element := range to.Elements {
+		switch e := element.(type) {
+		case *Section:
+			d.section(fromSections[e.ID], e)
+			for _, q := range e.Questions {
+				d.question(from.Question(q.ID), q)
+			}
+		case *Question:
+			d.question(from.Question(e.ID), e)
+		}
+	}
+
+	for _, element := range from.Elements {
+		if section, ok := element.(*Section); ok && toSections[section.ID] == nil {
+			d.add(ChangeRemoved, section.ID, "", summary(section), nil)
+		}
+	}
+	for _, q := range from.Questions() {
+		if to.Question(q.ID) == nil {
+			d.add(ChangeRemoved, q.ID, "", q, nil)
+		}
+	}
+
+	return d.changes
+}
+
+func sections(f *Form) map[string]*Section {
+	result := map[string]*Section{}
+	for _, element := range f.Elements {
+		if section, ok := element.(*Section); ok {
+			result[section.ID] = section
+		}
+	}
+	return result
+}
+
+// summary copies a section without its questions, which are diffed separately.
+func summary(s *Section) *Section {
+	section := *s
+	section.Questions = nil
+	return &section
+}
+
+func (d *differ) add(kind ChangeKind, element, field string, old, new interface{}) {
+	d.changes = append(d.changes, Change{
+		Kind:    kind,
+		Element: element,
+		Field:   field,
+		Old:     old,
+		New:     new,
+	})
+}
+
+func (d *differ) field(element, field string, old, new interface{}) {
+	if !reflect.DeepEqual(old, new) {
+		d.add(ChangeModified, element, field, old, new)
+	}
+}
+
+func (d *differ) section(old, new *Section) {
+	if old == nil {
+		d.add(ChangeAdded, new.ID, "", nil, summary(new))
+		return
+	}
+
+	d.field(new.ID, "title", old.Title, new.Title)
+	d.field(new.ID, "description", old.Description, new.Description)
+	d.field(new.ID, "visible_if", old.VisibleIf, new.VisibleIf)
+}
+
+func (d *differ) question(old, new *Question) {
+	if old == nil {
+		d.add(ChangeAdded, new.ID, "", nil, new)
+		return
+	}
+
+	id := new.ID
+	d.field(id, "type", old.Type, new.Type)
+	d.field(id, "required", old.Required, new.Required)
+	d.field(id, "title", old.Title, new.Title)
+	d.field(id, "description", old.Description, new.Description)
+	d.field(id, "placeholder", old.Placeholder, new.Placeholder)
+	d.field(id, "visible_if", old.VisibleIf, new.VisibleIf)
+	d.field(id, "section", old.Section, new.Section)
+
+	d.options(id, "options", old.Options, new.Options)
+	d.options(id, "categories", old.Categories, new.Categories)
+
+	d.field(id, "validations.regex", old.Validations.Regex, new.Validations.Regex)
+	d.field(id, "validations.min_chars", old.Validations.MinChars, new.Validations.MinChars)
+	d.field(id, "validations.max_chars", old.Validations.MaxChars, new.Validations.MaxChars)
+	d.field(id, "validations.min_words", old.Validations.MinWords, new.Validations.MinWords)
+	d.field(id, "validations.max_words", old.Validations.MaxWords, new.Validations.MaxWords)
+
+	d.field(id, "icon", old.Icon, new.Icon)
+	d.field(id, "steps", old.Steps, new.Steps)
+	d.field(id, "min_label", old.MinLabel, new.MinLabel)
+	d.field(id, "max_label", old.MaxLabel, new.MaxLabel)
+
+	d.field(id, "max_file_size", old.MaxFileSize, new.MaxFileSize)
+	d.field(id, "max_files", old.MaxFiles, new.MaxFiles)
+	d.field(id, "allowed_types", old.AllowedTypes, new.AllowedTypes)
+
+	d.field(id, "correct", old.Correct, new.Correct)
+	d.field(id, "points", old.Points, new.Points)
+}
+
+// options diffs a list of options or categories, matching them by value. An
+// option whose label changed is reported as modified.
+func (d *differ) options(element, field string, old, new []Option) {
+	find := func(options []Option, value string) *Option {
+		for i := range options {
+			if options[i].Value == value {
+				return &options[i]
+			}
+		}
+		return nil
+	}
+
+	for _, option := range new {
+		previous := find(old, option.Value)
+		if previous == nil {
+			d.add(ChangeAdded, element, field, nil, option)
+		} else if previous.Label != option.Label {
+			d.add(ChangeModified, element, field, *previous, option)
+		}
+	}
+
+	for _, option := range old {
+		if find(new, option.Value) == nil {
+			d.add(ChangeRemoved, element, field, option, nil)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package spec
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	from := mustParse(t, `form {
+	title "Feedback"
+	section id="about" {
+		title "About you"
+		question id="name" type="input" {
+			title "Name"
+		}
+	}
+	question id="pace" type="radio" {
+		title "Pace"
+		option value="slow"
+		option value="fast" label="Fast"
+	}
+	question id="removed" type="date" {
+		title "Removed"
+	}
+}`)
+	to := mustParse(t, `form {
+	title "Course feedback"
+	section id="about" {
+		title "About you"
+		question id="name" type="input" required {
+			title "Name"
+			validations {
+				max-chars 20
+			}
+		}
+	}
+	question id="pace" type="radio" {
+		title "Pace"
+		option value="fast" label="Too fast"
+		option value="good"
+	}
+	question id="added" type="textarea" {
+		title "Added"
+	}
+}`)
+
+	changes := Diff(from, to)
+
+	want := []struct {
+		kind    ChangeKind
+		element string
+		field   string
+	}{
+		{ChangeModified, "", "title"},
+		{ChangeModified, "name", "required"},
+		{ChangeModified, "name", "validations.max_chars"},
+		{ChangeModified, "pace", "options"},
+		{ChangeAdded, "pace", "options"},
+		{ChangeRemoved, "pace", "options"},
+		{ChangeAdded, "added", ""},
+		{ChangeRemoved, "removed", ""},
+	}
+
+	if len(changes) != len(want) {
+		t.Fatalf("Diff() = %+v, want %d changes", changes, len(want))
+	}
+	for i, w := range want {
+		c := changes[i]
+		if c.Kind != w.kind || c.Element != w.element || c.Field != w.field {
+			t.Errorf("Diff()[%d] = %s %s %s, want %s %s %s",
+				i, c.Kind, c.Element, c.Field, w.kind, w.element, w.field)
+		}
+	}
+
+	if changes[0].Old != "Feedback" || changes[0].New != "Course feedback" {
+		t.Errorf("Diff()[0] = %v -> %v", changes[0].Old, changes[0].New)
+	}
+	if changes[3].Old != (Option{"fast", "Fast"}) || changes[3].New != (Option{"fast", "Too fast"}) {
+		t.Errorf("Diff()[3] = %v -> %v", changes[3].Old, changes[3].New)
+	}
+}
+
+func TestDiffUnchanged(t *testing.T) {
+	if changes := Diff(mustParse(t, conditional), mustParse(t, conditional)); len(changes) != 0 {
+		t.Errorf("Diff() = %+v, want no changes", changes)
+	}
+}
+
+func TestDiffSections(t *testing.T) {
+	from := mustParse(t, "form {\n\tsection id=\"a\"\n}")
+	to := mustParse(t, "form {\n\tsection id=\"b\" {\n\t\ttitle \"B\"\n\t}\n}")
+
+	changes := Diff(from, to)
+	if len(changes) != 2 || changes[0].Kind != ChangeAdded || changes[0].Element != "b" ||
+		changes[1].Kind != ChangeRemoved || changes[1].Element != "a" {
+		t.Fatalf("Diff() = %+v", changes)
+	}
+
+	if section, ok := changes[0].New.(*Section); !ok || section.Title != "B" || section.Questions != nil {
+		t.Errorf("Diff()[0].New = %+v, want the section without its questions", changes[0].New)
+	}
+}
+
+func TestDiffAnswerKey(t *testing.T) {
+	from := mustParse(t, quiz)
+	to := mustParse(t, strings.NewReplacer(`correct "paris"`, `correct "lyon"`, "points 3", "points 5").Replace(quiz))
+
+	changes := Diff(from, to)
+	if len(changes) != 2 {
+		t.Fatalf("Diff() = %+v, want 2 changes", changes)
+	}
+
+	if c := changes[0]; c.Element != "capital" || c.Field != "correct" {
+		t.Errorf("Diff()[0] = %s %s, want capital correct", c.Element, c.Field)
+	}
+	if c := changes[1]; c.Element != "bonus" || c.Field != "points" || c.Old != int64(3) || c.New != int64(5) {
+		t.Errorf("Diff()[1] = %s %s %v -> %v, want bonus points 3 -> 5", c.Element, c.Field, c.Old, c.New)
+	}
+}
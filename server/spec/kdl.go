@@ -0,0 +1,776 @@
+package spec
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// this file implements a small KDL parser, covering the parts of the language
+// that form specifications make use of: nodes, arguments, properties,
+// children, comments (including slashdash), line continuations, quoted and
+// raw strings, numbers and keywords. both `true` and `#true` style keywords
+// are accepted, as are bare identifiers in value position. parsed documents
+// can be written back out, without their comments and type annotations.
+
+const eof = -1
+
+type value struct {
+	Raw    interface{} // string, int64, float64, bool or nil
+	Bare   bool        // written as an identifier rather than a quoted string
+	Line   int
+	Column int
+}
+
+type node struct {
+	Name     string
+	Args     []value
+	Props    map[string]value
+	Keys     []string // property names, in the order they were written
+	Children []*node
+	Line     int
+	Column   int
+}
+
+type parser struct {
+	src  []rune
+	pos  int
+	line int
+	col  int
+}
+
+func parseDocument(source string) ([]*node, error) {
+	p := &parser{src: []rune(source), line: 1, col: 1}
+
+	nodes, err := p.nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() != eof {
+		return nil, p.errorf(p.line, p.col, "unexpected '}'")
+	}
+
+	return nodes, nil
+}
+
+func (p *parser) errorf(line, col int, format string, args ...interface{}) error {
+	return Errors{{Line: line, Column: col, Message: fmt.Sprintf(format, args...)}}
+}
+
+func (p *parser) peek() rune {
+	return p.peekAt(0)
+}
+
+func (p *parser) peekAt(offset int) rune {
+	if p.pos+offset >= len(p.src) {
+		return eof
+	}
+	return p.src[p.pos+offset]
+}
+
+func (p *parser) next() rune {
+	r := p.peek()
+	if r == eof {
+		return eof
+	}
+
+	p.pos++
+	if isNewline(r) && !(r == '\r' && p.peek() == '\n') {
+		p.line++
+		p.col = 1
+	} else {
+		p.col++
+	}
+
+	return r
+}
+
+func (p *parser) nodes() ([]*node, error) {
+	var nodes []*node
+
+	for {
+		if err := p.skipLineSpace(); err != nil {
+			return nil, err
+		}
+
+		r := p.peek()
+		if r == eof || r == '}' {
+			return nodes, nil
+		}
+
+		slashdash := false
+		if r == '/' && p.peekAt(1) == '-' {
+			p.next()
+			p.next()
+			slashdash = true
+
+			if err := p.skipLineSpace(); err != nil {
+				return nil, err
+			}
+		}
+
+		n, err := p.node()
+		if err != nil {
+			return nil, err
+		}
+
+		if !slashdash {
+			nodes = append(nodes, n)
+		}
+	}
+}
+
+func (p *parser) node() (*node, error) {
+	line, col := p.line, p.col
+
+	if err := p.skipTypeAnnotation(); err != nil {
+		return nil, err
+	}
+
+	name, ok, err := p.identifierOrString()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, p.errorf(p.line, p.col, "expected a node name, found %s", describe(p.peek()))
+	}
+
+	n := &node{Name: name, Props: map[string]value{}, Line: line, Column: col}
+	hasChildren := false
+
+	for {
+		spaced, err := p.skipNodeSpace()
+		if err != nil {
+			return nil, err
+		}
+
+		r := p.peek()
+		switch {
+		case r == eof || r == '}':
+			return n, nil
+		case r == ';' || isNewline(r):
+			p.next()
+			return n, nil
+		case r == '/' && p.peekAt(1) == '/':
+			p.skipLineComment()
+			return n, nil
+		}
+
+		slashdash := false
+		if r == '/' && p.peekAt(1) == '-' {
+			p.next()
+			p.next()
+			slashdash = true
+
+			if _, err := p.skipNodeSpace(); err != nil {
+				return nil, err
+			}
+			r = p.peek()
+		}
+
+		if r == '{' {
+			children, err := p.children()
+			if err != nil {
+				return nil, err
+			}
+
+			if !slashdash {
+				n.Children = append(n.Children, children...)
+				hasChildren = true
+			}
+			continue
+		}
+
+		if hasChildren {
+			return nil, p.errorf(p.line, p.col, "unexpected %s after children block", describe(r))
+		}
+		if !spaced && !slashdash {
+			return nil, p.errorf(p.line, p.col, "expected whitespace, found %s", describe(r))
+		}
+
+		key, v, err := p.entry()
+		if err != nil {
+			return nil, err
+		}
+
+		if slashdash {
+			continue
+		}
+
+		if key != nil {
+			if _, present := n.Props[*key]; !present {
+				n.Keys = append(n.Keys, *key)
+			}
+			n.Props[*key] = v
+		} else {
+			n.Args = append(n.Args, v)
+		}
+	}
+}
+
+func (p *parser) children() ([]*node, error) {
+	line, col := p.line, p.col
+	p.next() // opening brace
+
+	children, err := p.nodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek() != '}' {
+		return nil, p.errorf(line, col, "unclosed '{'")
+	}
+	p.next()
+
+	return children, nil
+}
+
+// entry parses either an argument or a property, returning a nil key for the
+// former.
+func (p *parser) entry() (*string, value, error) {
+	line, col := p.line, p.col
+
+	if err := p.skipTypeAnnotation(); err != nil {
+		return nil, value{}, err
+	}
+
+	r := p.peek()
+	if p.isNumberStart() || p.isKeywordStart() {
+		v, err := p.value(line, col)
+		return nil, v, err
+	}
+
+	quoted := r == '"' || r == '#' || p.isRawStringStart()
+	text, ok, err := p.identifierOrString()
+	if err != nil {
+		return nil, value{}, err
+	}
+	if !ok {
+		return nil, value{}, p.errorf(line, col, "unexpected %s", describe(r))
+	}
+
+	if p.peek() == '=' {
+		p.next()
+		vline, vcol := p.line, p.col
+
+		if err := p.skipTypeAnnotation(); err != nil {
+			return nil, value{}, err
+		}
+		v, err := p.value(vline, vcol)
+		if err != nil {
+			return nil, value{}, err
+		}
+
+		return &text, v, nil
+	}
+
+	v := value{Raw: text, Line: line, Column: col}
+	if !quoted {
+		v.Raw, v.Bare = bareKeyword(text), true
+	}
+
+	return nil, v, nil
+}
+
+func (p *parser) value(line, col int) (value, error) {
+	r := p.peek()
+	if p.isNumberStart() {
+		return p.number(line, col)
+	}
+	if p.isKeywordStart() {
+		return p.keyword(line, col)
+	}
+
+	quoted := r == '"' || r == '#' || p.isRawStringStart()
+	text, ok, err := p.identifierOrString()
+	if err != nil {
+		return value{}, err
+	}
+	if !ok {
+		return value{}, p.errorf(line, col, "expected a value, found %s", describe(r))
+	}
+
+	if quoted {
+		return value{Raw: text, Line: line, Column: col}, nil
+	}
+	return value{Raw: bareKeyword(text), Bare: true, Line: line, Column: col}, nil
+}
+
+func bareKeyword(text string) interface{} {
+	switch text {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+	return text
+}
+
+func (p *parser) keyword(line, col int) (value, error) {
+	p.next() // hash
+
+	var b strings.Builder
+	for isIdentifierChar(p.peek()) {
+		b.WriteRune(p.next())
+	}
+
+	switch b.String() {
+	case "true":
+		return value{Raw: true, Line: line, Column: col}, nil
+	case "false":
+		return value{Raw: false, Line: line, Column: col}, nil
+	case "null":
+		return value{Raw: nil, Line: line, Column: col}, nil
+	}
+
+	return value{}, p.errorf(line, col, "unknown keyword '#%s'", b.String())
+}
+
+func (p *parser) number(line, col int) (value, error) {
+	var b strings.Builder
+	for isIdentifierChar(p.peek()) {
+		b.WriteRune(p.next())
+	}
+
+	text := b.String()
+	digits := strings.ReplaceAll(text, "_", "")
+
+	sign := ""
+	if digits[0] == '-' || digits[0] == '+' {
+		sign, digits = digits[:1], digits[1:]
+	}
+
+	base := 10
+	if len(digits) > 2 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x':
+			base = 16
+		case 'o':
+			base = 8
+		case 'b':
+			base = 2
+		}
+		if base != 10 {
+			digits = digits[2:]
+		}
+	}
+
+	if base == 10 && strings.ContainsAny(digits, ".eE") {
+		f, err := strconv.ParseFloat(sign+digits, 64)
+		if err != nil {
+			return value{}, p.errorf(line, col, "invalid number '%s'", text)
+		}
+		return value{Raw: f, Line: line, Column: col}, nil
+	}
+
+	i, err := strconv.ParseInt(sign+digits, base, 64)
+	if err != nil {
+		return value{}, p.errorf(line, col, "invalid number '%s'", text)
+	}
+	return value{Raw: i, Line: line, Column: col}, nil
+}
+
+// identifierOrString parses a bare identifier, a quoted string or a raw
+// string. the returned boolean is false if none of those start here.
+func (p *parser) identifierOrString() (string, bool, error) {
+	r := p.peek()
+
+	switch {
+	case r == '"':
+		s, err := p.quotedString()
+		return s, true, err
+	case r == '#' && (p.peekAt(1) == '"' || p.peekAt(1) == '#'):
+		s, err := p.rawString()
+		return s, true, err
+	case p.isRawStringStart():
+		p.next() // r
+		s, err := p.rawString()
+		return s, true, err
+	case isIdentifierChar(r) && !isDigit(r):
+		var b strings.Builder
+		for isIdentifierChar(p.peek()) {
+			b.WriteRune(p.next())
+		}
+		return b.String(), true, nil
+	}
+
+	return "", false, nil
+}
+
+func (p *parser) isNumberStart() bool {
+	r := p.peek()
+	return isDigit(r) || ((r == '-' || r == '+') && isDigit(p.peekAt(1)))
+}
+
+func (p *parser) isKeywordStart() bool {
+	r := p.peekAt(1)
+	return p.peek() == '#' && r != '"' && r != '#'
+}
+
+func (p *parser) isRawStringStart() bool {
+	if p.peek() != 'r' {
+		return false
+	}
+	r := p.peekAt(1)
+	return r == '"' || r == '#'
+}
+
+func (p *parser) quotedString() (string, error) {
+	line, col := p.line, p.col
+	p.next() // opening quote
+
+	var b strings.Builder
+	for {
+		r := p.next()
+		switch r {
+		case eof:
+			return "", p.errorf(line, col, "unterminated string")
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+}
+
+func (p *parser) escape(b *strings.Builder) error {
+	line, col := p.line, p.col-1
+
+	r := p.next()
+	switch r {
+	case 'n':
+		b.WriteRune('\n')
+	case 'r':
+		b.WriteRune('\r')
+	case 't':
+		b.WriteRune('\t')
+	case 'b':
+		b.WriteRune('\b')
+	case 'f':
+		b.WriteRune('\f')
+	case 's':
+		b.WriteRune(' ')
+	case '\\', '/', '"':
+		b.WriteRune(r)
+	case 'u':
+		if p.next() != '{' {
+			return p.errorf(line, col, "invalid unicode escape")
+		}
+
+		var hex strings.Builder
+		for p.peek() != '}' && p.peek() != eof && hex.Len() <= 6 {
+			hex.WriteRune(p.next())
+		}
+		if p.next() != '}' {
+			return p.errorf(line, col, "invalid unicode escape")
+		}
+
+		code, err := strconv.ParseUint(hex.String(), 16, 32)
+		if err != nil || code > unicode.MaxRune {
+			return p.errorf(line, col, "invalid unicode escape")
+		}
+		b.WriteRune(rune(code))
+	default:
+		if !isWhitespace(r) && !isNewline(r) {
+			return p.errorf(line, col, "invalid escape sequence '\\%c'", r)
+		}
+
+		// a backslash followed by whitespace discards all of it
+		for isWhitespace(p.peek()) || isNewline(p.peek()) {
+			p.next()
+		}
+	}
+
+	return nil
+}
+
+func (p *parser) rawString() (string, error) {
+	line, col := p.line, p.col
+
+	hashes := 0
+	for p.peek() == '#' {
+		p.next()
+		hashes++
+	}
+	if p.next() != '"' {
+		return "", p.errorf(line, col, "invalid raw string")
+	}
+
+	var b strings.Builder
+	for {
+		r := p.next()
+		if r == eof {
+			return "", p.errorf(line, col, "unterminated string")
+		}
+
+		if r == '"' {
+			closed := true
+			for i := 0; i < hashes; i++ {
+				if p.peekAt(i) != '#' {
+					closed = false
+					break
+				}
+			}
+
+			if closed {
+				for i := 0; i < hashes; i++ {
+					p.next()
+				}
+				return b.String(), nil
+			}
+		}
+
+		b.WriteRune(r)
+	}
+}
+
+func (p *parser) skipTypeAnnotation() error {
+	if p.peek() != '(' {
+		return nil
+	}
+
+	line, col := p.line, p.col
+	p.next()
+
+	if _, ok, err := p.identifierOrString(); err != nil {
+		return err
+	} else if !ok || p.peek() != ')' {
+		return p.errorf(line, col, "invalid type annotation")
+	}
+	p.next()
+
+	return nil
+}
+
+// skipLineSpace skips whitespace, newlines and comments between nodes.
+func (p *parser) skipLineSpace() error {
+	for {
+		r := p.peek()
+		switch {
+		case isWhitespace(r) || isNewline(r):
+			p.next()
+		case r == '/' && p.peekAt(1) == '/':
+			p.skipLineComment()
+		case r == '/' && p.peekAt(1) == '*':
+			if err := p.skipBlockComment(); err != nil {
+				return err
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// skipNodeSpace skips whitespace, block comments and line continuations
+// within a node, reporting whether anything was skipped.
+func (p *parser) skipNodeSpace() (bool, error) {
+	skipped := false
+
+	for {
+		r := p.peek()
+		switch {
+		case isWhitespace(r):
+			p.next()
+		case r == '/' && p.peekAt(1) == '*':
+			if err := p.skipBlockComment(); err != nil {
+				return false, err
+			}
+		case r == '\\':
+			line, col := p.line, p.col
+			p.next()
+
+			for isWhitespace(p.peek()) {
+				p.next()
+			}
+
+			if p.peek() == '/' && p.peekAt(1) == '/' {
+				p.skipLineComment()
+			} else if isNewline(p.peek()) {
+				p.next()
+			} else if p.peek() != eof {
+				return false, p.errorf(line, col, "expected a newline after line continuation")
+			}
+		default:
+			return skipped, nil
+		}
+
+		skipped = true
+	}
+}
+
+// skipLineComment skips a line comment along with the terminating newline.
+func (p *parser) skipLineComment() {
+	for {
+		r := p.next()
+		if r == eof || isNewline(r) && !(r == '\r' && p.peek() == '\n') {
+			return
+		}
+	}
+}
+
+func (p *parser) skipBlockComment() error {
+	line, col := p.line, p.col
+	p.next()
+	p.next()
+
+	depth := 1
+	for depth > 0 {
+		r := p.next()
+		switch {
+		case r == eof:
+			return p.errorf(line, col, "unterminated block comment")
+		case r == '/' && p.peek() == '*':
+			p.next()
+			depth++
+		case r == '*' && p.peek() == '/':
+			p.next()
+			depth--
+		}
+	}
+
+	return nil
+}
+
+func describe(r rune) string {
+	switch {
+	case r == eof:
+		return "end of input"
+	case isNewline(r):
+		return "newline"
+	}
+	return fmt.Sprintf("'%c'", r)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isNewline(r rune) bool {
+	switch r {
+	case '\n', '\r', '\u0085', '\u000c', '\u2028', '\u2029':
+		return true
+	}
+	return false
+}
+
+func isWhitespace(r rune) bool {
+	switch r {
+	case '\t', ' ', '\u00a0', '\u1680', '\u202f', '\u205f', '\u3000', '\ufeff':
+		return true
+	}
+	return r >= '\u2000' && r <= '\u200a'
+}
+
+func isIdentifierChar(r rune) bool {
+	if r == eof || r < 0x20 || isWhitespace(r) || isNewline(r) {
+		return false
+	}
+	return !strings.ContainsRune(`\/(){}<>;[]=,"#`, r)
+}
+
+// formatDocument writes nodes out as a KDL document, indented with tabs.
+func formatDocument(nodes []*node) string {
+	var b strings.Builder
+	formatNodes(&b, nodes, 0)
+	return b.String()
+}
+
+func formatNodes(b *strings.Builder, nodes []*node, depth int) {
+	for _, n := range nodes {
+		b.WriteString(strings.Repeat("\t", depth))
+		b.WriteString(formatIdentifier(n.Name))
+
+		for _, arg := range n.Args {
+			b.WriteString(" " + formatValueSource(arg))
+		}
+		for _, key := range n.Keys {
+			b.WriteString(" " + formatIdentifier(key) + "=" + formatValueSource(n.Props[key]))
+		}
+
+		if len(n.Children) > 0 {
+			b.WriteString(" {\n")
+			formatNodes(b, n.Children, depth+1)
+			b.WriteString(strings.Repeat("\t", depth) + "}")
+		}
+		b.WriteString("\n")
+	}
+}
+
+// formatIdentifier writes a node name or property key bare if it would be
+// read back the same way, and quoted otherwise.
+func formatIdentifier(text string) string {
+	p := &parser{src: []rune(text)}
+	bare := text != "" && !isDigit(p.peek()) && !p.isNumberStart() && bareKeyword(text) == text
+	for _, r := range text {
+		if !isIdentifierChar(r) {
+			bare = false
+		}
+	}
+
+	if bare {
+		return text
+	}
+	return formatString(text)
+}
+
+func formatValueSource(v value) string {
+	switch raw := v.Raw.(type) {
+	case string:
+		if v.Bare {
+			return formatIdentifier(raw)
+		}
+		return formatString(raw)
+	case int64:
+		return strconv.FormatInt(raw, 10)
+	case float64:
+		// written so that it is read back as a float rather than an integer
+		text := strconv.FormatFloat(raw, 'g', -1, 64)
+		if !strings.ContainsAny(text, ".e") {
+			text += ".0"
+		}
+		return text
+	case bool:
+		return "#" + strconv.FormatBool(raw)
+	}
+	return "#null"
+}
+
+func formatString(text string) string {
+	var b strings.Builder
+	b.WriteRune('"')
+
+	for _, r := range text {
+		switch r {
+		case '"', '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\f':
+			b.WriteString(`\f`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, "\\u{%x}", r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+
+	b.WriteRune('"')
+	return b.String()
+}
@@ -0,0 +1,195 @@
+package spec
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Form is the parsed representation of a form specification, as documented in
+// `docs/spec.md`.
+type Form struct {
+	Version     int64     `json:"version"`
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Elements    []Element `json:"elements"`
+
+	questions map[string]*Question
+}
+
+// Element is a top level item of a form, either a *Section or a *Question.
+type Element interface {
+	ElementID() string
+}
+
+type Section struct {
+	ID          string      `json:"id"`
+	Title       string      `json:"title,omitempty"`
+	Description string      `json:"description,omitempty"`
+	VisibleIf   *Condition  `json:"visible_if,omitempty"`
+	Questions   []*Question `json:"questions,omitempty"`
+}
+
+type QuestionType string
+
+const (
+	TypeInput         QuestionType = "input"
+	TypeTextarea      QuestionType = "textarea"
+	TypeRadio         QuestionType = "radio"
+	TypeCheckbox      QuestionType = "checkbox"
+	TypeSelect        QuestionType = "select"
+	TypeLikert        QuestionType = "likert"
+	TypeMatrix        QuestionType = "matrix"
+	TypeDate          QuestionType = "date"
+	TypeFile          QuestionType = "file"
+	TypeSectionHeader QuestionType = "section-header"
+)
+
+var questionTypes = []QuestionType{
+	TypeInput, TypeTextarea, TypeRadio, TypeCheckbox, TypeSelect,
+	TypeLikert, TypeMatrix, TypeDate, TypeFile, TypeSectionHeader,
+}
+
+type Question struct {
+	ID          string       `json:"id"`
+	Type        QuestionType `json:"type"`
+	Required    bool         `json:"required"`
+	Title       string       `json:"title"`
+	Description string       `json:"description,omitempty"`
+	Placeholder string       `json:"placeholder,omitempty"`
+	VisibleIf   *Condition   `json:"visible_if,omitempty"`
+
+	Options     []Option    `json:"options,omitempty"`
+	Categories  []Option    `json:"categories,omitempty"`
+	Validations Validations `json:"validations"`
+
+	// only applicable to likert questions
+	Icon     string `json:"icon,omitempty"`
+	Steps    int64  `json:"steps,omitempty"`
+	MinLabel string `json:"min_label,omitempty"`
+	MaxLabel string `json:"max_label,omitempty"`
+
+	// only applicable to file questions
+	MaxFileSize  *float64 `json:"max_file_size,omitempty"` // in megabytes
+	MaxFiles     *int64   `json:"max_files,omitempty"`
+	AllowedTypes []string `json:"allowed_types,omitempty"`
+
+	// answer key, used to score quizzes
+	Correct []string `json:"correct,omitempty"`
+	Points  int64    `json:"points,omitempty"`
+
+	Section string `json:"section,omitempty"` // id of the enclosing section
+	Line    int    `json:"-"`
+	Column  int    `json:"-"`
+}
+
+// Option is used for both the options and the categories of a question.
+type Option struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+type Validations struct {
+	Regex    *string `json:"regex,omitempty"`
+	MinChars *int64  `json:"min_chars,omitempty"`
+	MaxChars *int64  `json:"max_chars,omitempty"`
+	MinWords *int64  `json:"min_words,omitempty"`
+	MaxWords *int64  `json:"max_words,omitempty"`
+}
+
+func (s *Section) ElementID() string  { return s.ID }
+func (q *Question) ElementID() string { return q.ID }
+
+// Question returns the question with the given id, or nil if there is none.
+func (f *Form) Question(id string) *Question {
+	return f.questions[id]
+}
+
+// Questions returns every question in the form, in the order they appear.
+func (f *Form) Questions() []*Question {
+	var questions []*Question
+	for _, element := range f.Elements {
+		switch e := element.(type) {
+		case *Section:
+			questions = append(questions, e.Questions...)
+		case *Question:
+			questions = append(questions, e)
+		}
+	}
+	return questions
+}
+
+// HasOptions reports whether answers to this question are picked from its
+// list of options.
+func (q *Question) HasOptions() bool {
+	switch q.Type {
+	case TypeRadio, TypeCheckbox, TypeSelect, TypeMatrix:
+		return true
+	}
+	return false
+}
+
+// Option returns the option with the given value, or nil if there is none.
+func (q *Question) Option(value string) *Option {
+	for i := range q.Options {
+		if q.Options[i].Value == value {
+			return &q.Options[i]
+		}
+	}
+	return nil
+}
+
+// Category returns the category with the given value, or nil if there is none.
+func (q *Question) Category(value string) *Option {
+	for i := range q.Categories {
+		if q.Categories[i].Value == value {
+			return &q.Categories[i]
+		}
+	}
+	return nil
+}
+
+// Error is a problem found in a form specification, located by the line and
+// column it occurs at.
+type Error struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, ", ")
+}
+
+// Parse parses and validates a form specification. Any problems with it are
+// returned as Errors.
+func Parse(source string) (*Form, error) {
+	nodes, err := parseDocument(source)
+	if err != nil {
+		return nil, err
+	}
+
+	b := builder{}
+	form := b.document(nodes)
+	if len(b.errors) > 0 {
+		slices.SortStableFunc(b.errors, func(x, y Error) int {
+			if x.Line != y.Line {
+				return x.Line - y.Line
+			}
+			return x.Column - y.Column
+		})
+		return nil, b.errors
+	}
+
+	return form, nil
+}
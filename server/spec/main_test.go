@@ -0,0 +1,352 @@
+package spec
+
+import (
+	"errors"
+	"testing"
+)
+
+const example = `form {
+	version 1
+	title "Course feedback"
+
+	question id="semester" type="input" required {
+		title "Which semester are you taking this course in?"
+		validations {
+			regex "^[mMsS][0-9][0-9]$"
+			max-chars 3
+		}
+	}
+
+	section id="lectures" {
+		title "Lectures"
+
+		question id="rating" type="likert" {
+			title "How were the lectures?"
+		}
+
+		question id="pace" type="radio" required {
+			title "How was the pace?"
+			option value="slow" label="Too Slow"
+			option value="good"
+			option value=3 label="Too Fast"
+		}
+	}
+
+	question id="grades" type="matrix" {
+		title "Rate the assignments."
+		category value="relevance"
+		option value="low"
+		option value="high"
+	}
+
+	question id="files" type="file" {
+		title "Attach your notes."
+		max-file-size 512 "kb"
+		max-files 2
+	}
+}
+`
+
+func TestParse(t *testing.T) {
+	form, err := Parse(example)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if form.Version != 1 || form.Title != "Course feedback" {
+		t.Errorf("form = version %d, title %q", form.Version, form.Title)
+	}
+
+	if len(form.Elements) != 4 {
+		t.Fatalf("len(Elements) = %d, want 4", len(form.Elements))
+	}
+	section, ok := form.Elements[1].(*Section)
+	if !ok || section.ID != "lectures" || len(section.Questions) != 2 {
+		t.Fatalf("Elements[1] = %#v, want section lectures with 2 questions", form.Elements[1])
+	}
+
+	ids := []string{}
+	for _, q := range form.Questions() {
+		ids = append(ids, q.ID)
+	}
+	want := []string{"semester", "rating", "pace", "grades", "files"}
+	if len(ids) != len(want) {
+		t.Fatalf("Questions() = %v, want %v", ids, want)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("Questions() = %v, want %v", ids, want)
+		}
+	}
+
+	semester := form.Question("semester")
+	if !semester.Required || semester.Validations.Regex == nil || *semester.Validations.MaxChars != 3 {
+		t.Errorf("semester = %+v", semester)
+	}
+	if semester.Line != 5 || semester.Column != 2 {
+		t.Errorf("semester at %d:%d, want 5:2", semester.Line, semester.Column)
+	}
+
+	if rating := form.Question("rating"); rating.Steps != 5 || rating.Section != "lectures" {
+		t.Errorf("rating = steps %d, section %q, want 5, lectures", rating.Steps, rating.Section)
+	}
+
+	pace := form.Question("pace")
+	options := []Option{{"slow", "Too Slow"}, {"good", "good"}, {"3", "Too Fast"}}
+	if len(pace.Options) != len(options) {
+		t.Fatalf("pace.Options = %v, want %v", pace.Options, options)
+	}
+	for i := range options {
+		if pace.Options[i] != options[i] {
+			t.Errorf("pace.Options[%d] = %v, want %v", i, pace.Options[i], options[i])
+		}
+	}
+
+	files := form.Question("files")
+	if *files.MaxFileSize != 0.5 || *files.MaxFiles != 2 {
+		t.Errorf("files = max size %v, max files %v", *files.MaxFileSize, *files.MaxFiles)
+	}
+
+	if form.Question("lectures") != nil {
+		t.Errorf("Question() returned a section")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   Errors
+	}{
+		{
+			name:   "empty",
+			source: "",
+			want:   Errors{{1, 1, "missing 'form' node"}},
+		},
+		{
+			name:   "syntax",
+			source: "form {\n\ttitle \"unterminated\n}",
+			want:   Errors{{2, 8, "unterminated string"}},
+		},
+		{
+			name:   "unclosed",
+			source: "form {\n\ttitle \"x\"\n",
+			want:   Errors{{1, 6, "unclosed '{'"}},
+		},
+		{
+			name:   "unexpected root",
+			source: "forms {}\nform {}\nform {}",
+			want: Errors{
+				{1, 1, "unexpected node 'forms', expected 'form'"},
+				{3, 1, "only one 'form' node is allowed"},
+			},
+		},
+		{
+			name:   "unsupported version",
+			source: "form {\n\tversion 2\n}",
+			want:   Errors{{2, 2, "unsupported version 2"}},
+		},
+		{
+			name:   "duplicate title",
+			source: "form {\n\ttitle \"a\"\n\ttitle \"b\"\n}",
+			want:   Errors{{3, 2, "duplicate 'title'"}},
+		},
+		{
+			name:   "title not a string",
+			source: "form {\n\ttitle 1\n}",
+			want:   Errors{{2, 8, "title must be a string"}},
+		},
+		{
+			name:   "unknown node",
+			source: "form {\n\tpage id=\"a\"\n}",
+			want:   Errors{{2, 2, "unexpected node 'page' in form"}},
+		},
+		{
+			name:   "missing id",
+			source: "form {\n\tquestion type=\"input\" {\n\t\ttitle \"a\"\n\t}\n}",
+			want:   Errors{{2, 2, "question is missing an id"}},
+		},
+		{
+			name:   "empty id",
+			source: "form {\n\tsection id=\"\"\n}",
+			want:   Errors{{2, 13, "section id must be a non-empty string"}},
+		},
+		{
+			name: "duplicate id",
+			source: "form {\n\tsection id=\"a\"\n" +
+				"\tquestion id=\"a\" type=\"date\" {\n\t\ttitle \"a\"\n\t}\n}",
+			want: Errors{{3, 14, "duplicate id 'a'"}},
+		},
+		{
+			name:   "missing type and title",
+			source: "form {\n\tquestion id=\"a\"\n}",
+			want: Errors{
+				{2, 2, "question 'a' is missing a type"},
+				{2, 2, "question 'a' is missing a title"},
+			},
+		},
+		{
+			name:   "unknown type",
+			source: "form {\n\tquestion id=\"a\" type=\"slider\" {\n\t\ttitle \"a\"\n\t\tsteps 3\n\t}\n}",
+			want:   Errors{{2, 23, "unknown question type \"slider\""}},
+		},
+		{
+			name:   "unexpected argument",
+			source: "form {\n\tquestion id=\"a\" type=\"date\" optional {\n\t\ttitle \"a\"\n\t}\n}",
+			want:   Errors{{2, 30, "unexpected argument \"optional\""}},
+		},
+		{
+			name:   "required not a boolean",
+			source: "form {\n\tquestion id=\"a\" type=\"date\" required=\"yes\" {\n\t\ttitle \"a\"\n\t}\n}",
+			want:   Errors{{2, 39, "required must be true or false"}},
+		},
+		{
+			name:   "not applicable",
+			source: "form {\n\tquestion id=\"a\" type=\"date\" {\n\t\ttitle \"a\"\n\t\toption value=\"x\"\n\t}\n}",
+			want:   Errors{{4, 3, "'option' is not allowed on date questions"}},
+		},
+		{
+			name:   "empty option list",
+			source: "form {\n\tquestion id=\"a\" type=\"radio\" {\n\t\ttitle \"a\"\n\t}\n}",
+			want:   Errors{{2, 2, "radio question 'a' must have at least one option"}},
+		},
+		{
+			name:   "matrix without categories",
+			source: "form {\n\tquestion id=\"a\" type=\"matrix\" {\n\t\ttitle \"a\"\n\t\toption value=1\n\t}\n}",
+			want:   Errors{{2, 2, "matrix question 'a' must have at least one category and option"}},
+		},
+		{
+			name: "duplicate option",
+			source: "form {\n\tquestion id=\"a\" type=\"select\" {\n\t\ttitle \"a\"\n" +
+				"\t\toption value=1\n\t\toption value=\"1\"\n\t}\n}",
+			want: Errors{{5, 16, "duplicate option value '1'"}},
+		},
+		{
+			name:   "option without value",
+			source: "form {\n\tquestion id=\"a\" type=\"checkbox\" {\n\t\ttitle \"a\"\n\t\toption label=\"x\"\n\t}\n}",
+			want: Errors{
+				{2, 2, "checkbox question 'a' must have at least one option"},
+				{4, 3, "option is missing a value"},
+			},
+		},
+		{
+			name:   "steps too small",
+			source: "form {\n\tquestion id=\"a\" type=\"likert\" {\n\t\ttitle \"a\"\n\t\tsteps 1\n\t}\n}",
+			want:   Errors{{4, 3, "steps must be at least 2"}},
+		},
+		{
+			name:   "steps too large",
+			source: "form {\n\tquestion id=\"a\" type=\"likert\" {\n\t\ttitle \"a\"\n\t\tsteps 4000000000\n\t}\n}",
+			want:   Errors{{4, 3, "steps must be at most 100"}},
+		},
+		{
+			name:   "steps not an integer",
+			source: "form {\n\tquestion id=\"a\" type=\"likert\" {\n\t\ttitle \"a\"\n\t\tsteps 2.5\n\t}\n}",
+			want:   Errors{{4, 9, "steps must be an integer"}},
+		},
+		{
+			name:   "required section header",
+			source: "form {\n\tquestion id=\"a\" type=\"section-header\" required {\n\t\ttitle \"a\"\n\t}\n}",
+			want:   Errors{{2, 2, "section-header 'a' cannot be required"}},
+		},
+		{
+			name: "invalid validations",
+			source: "form {\n\tquestion id=\"a\" type=\"input\" {\n\t\ttitle \"a\"\n\t\tvalidations {\n" +
+				"\t\t\tregex \"[\"\n\t\t\tmin-chars 5\n\t\t\tmax-chars 2\n\t\t\tmax-words -1\n\t\t}\n\t}\n}",
+			want: Errors{
+				{4, 3, "min-chars must not exceed max-chars"},
+				{5, 4, "invalid regex: error parsing regexp: missing closing ]: `[`"},
+				{8, 4, "max-words must not be negative"},
+			},
+		},
+		{
+			name: "invalid file limits",
+			source: "form {\n\tquestion id=\"a\" type=\"file\" {\n\t\ttitle \"a\"\n" +
+				"\t\tmax-file-size 10 \"tb\"\n\t\tmax-files 0\n\t}\n}",
+			want: Errors{
+				{4, 20, "unknown unit \"tb\", expected kb, mb or gb"},
+				{5, 3, "max-files must be at least 1"},
+			},
+		},
+		{
+			name:   "unexpected property",
+			source: "form {\n\tsection id=\"a\" hidden=true\n}",
+			want:   Errors{{2, 24, "unexpected property 'hidden'"}},
+		},
+		{
+			name:   "nested section",
+			source: "form {\n\tsection id=\"a\" {\n\t\tsection id=\"b\"\n\t}\n}",
+			want:   Errors{{3, 3, "unexpected node 'section' in section"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form, err := Parse(tt.source)
+			if form != nil {
+				t.Errorf("Parse() returned a form")
+			}
+
+			var errs Errors
+			if !errors.As(err, &errs) {
+				t.Fatalf("Parse() error = %v, want Errors", err)
+			}
+
+			if len(errs) != len(tt.want) {
+				t.Fatalf("Parse() errors = %v, want %v", errs, tt.want)
+			}
+			for i := range tt.want {
+				if errs[i] != tt.want[i] {
+					t.Errorf("Parse() errors[%d] = %v, want %v", i, errs[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestErrorsSorted(t *testing.T) {
+	// the node is reported after its children, but sorted first
+	source := "form {\n\tquestion id=\"a\" type=\"radio\" {\n\t\ttitle 1\n\t}\n}"
+
+	_, err := Parse(source)
+	if err == nil || err.Error() != "2:2: radio question 'a' must have at least one option, 3:9: title must be a string" {
+		t.Errorf("Parse() error = %v", err)
+	}
+}
+
+func TestParseKDL(t *testing.T) {
+	source := `// a comment
+form /* inline */ {
+	title r#"raw "quoted" title"#
+	/-description "ignored"
+	question id=a type=date \
+		#true {
+		title "line\ncontinued"
+	}
+}`
+
+	_, err := Parse(source)
+	var errs Errors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0] != (Error{6, 3, "unexpected argument true"}) {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	form, err := Parse(`form {
+	title r#"raw "quoted" title"#
+	/-description "ignored"
+	question id=a type=date \
+		required {
+		title "line\ncontinued"
+	}
+}`)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if form.Title != `raw "quoted" title` || form.Description != "" {
+		t.Errorf("form = title %q, description %q", form.Title, form.Description)
+	}
+	if q := form.Question("a"); q == nil || !q.Required || q.Title != "line\ncontinued" {
+		t.Errorf("Question(a) = %+v", q)
+	}
+}
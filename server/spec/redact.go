@@ -0,0 +1,28 @@
+package spec
+
+// Redact removes the answer key from the source of a form structure, so that
+// it can be shown to respondents. The source is written out again from what
+// was parsed, leaving out comments and slashdashed nodes as well, since they
+// could hold the answer key too.
+func Redact(source string) (string, error) {
+	nodes, err := parseDocument(source)
+	if err != nil {
+		return "", err
+	}
+
+	var redact func(nodes []*node, inQuestion bool) []*node
+	redact = func(nodes []*node, inQuestion bool) []*node {
+		kept := make([]*node, 0, len(nodes))
+		for _, n := range nodes {
+			if inQuestion && n.Name == "correct" {
+				continue
+			}
+
+			n.Children = redact(n.Children, n.Name == "question")
+			kept = append(kept, n)
+		}
+		return kept
+	}
+
+	return formatDocument(redact(nodes, false)), nil
+}
@@ -0,0 +1,97 @@
+package spec
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	redacted, err := Redact(quiz)
+	if err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+
+	form := mustParse(t, redacted)
+	for _, q := range form.Questions() {
+		if q.Scored() {
+			t.Errorf("%s still has an answer key", q.ID)
+		}
+	}
+
+	// points are left for respondents to see what each question is worth
+	if q := form.Question("capital"); q.Points != 2 {
+		t.Errorf("capital.Points = %d, want 2", q.Points)
+	}
+	if q := form.Question("bonus"); q.VisibleIf == nil || q.Title != "When did the war end?" {
+		t.Errorf("bonus = %+v", q)
+	}
+}
+
+func TestRedactOnlyQuestions(t *testing.T) {
+	// a node named correct outside of a question is not part of an answer key
+	source := "form {\n\ttitle \"correct\"\n}\n"
+
+	redacted, err := Redact(source)
+	if err != nil || redacted != source {
+		t.Errorf("Redact() = %q, %v, want the source unchanged", redacted, err)
+	}
+
+	if _, err := Redact("form {"); err == nil {
+		t.Errorf("Redact() of an invalid document succeeded")
+	}
+}
+
+func TestRedactHidden(t *testing.T) {
+	source := `form {
+	// correct "paris"
+	question id="capital" type="radio" {
+		title "Capital" /* correct "paris" */
+		option value="paris"
+		option value="lyon"
+		/-correct "paris"
+		correct "paris" /-"lyon"
+	}
+	/* question id="old" type="input" {
+		correct "lyon"
+	} */
+	/-question id="draft" type="input" {
+		title "Draft"
+		correct "paris"
+	}
+}`
+
+	redacted, err := Redact(source)
+	if err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+	if strings.Contains(redacted, "correct") {
+		t.Errorf("Redact() = %q, want no trace of the answer key", redacted)
+	}
+	if !strings.Contains(redacted, `option value="paris"`) {
+		t.Errorf("Redact() = %q, want the options kept", redacted)
+	}
+}
+
+func TestRedactRoundTrip(t *testing.T) {
+	sources := []string{
+		example,
+		conditional,
+		"form {\n\ttitle \"Say \\\"hi\\\"\\n\\tnow \\u{1}\" \n\tquestion id=\"a b\" type=input \\\n\t\trequired=#true {\n\t\ttitle r#\"C:\\raw\"#\n\t}\n}",
+		"form {\n\tquestion id=\"size\" type=\"file\" {\n\t\ttitle \"Size\"\n\t\tmax-file-size 2.0 \"mb\"\n\t}\n}",
+	}
+
+	for _, source := range sources {
+		redacted, err := Redact(source)
+		if err != nil {
+			t.Fatalf("Redact() error = %v", err)
+		}
+
+		// positions are left out of the json, since they change
+		got, _ := json.Marshal(mustParse(t, redacted))
+		want, _ := json.Marshal(mustParse(t, source))
+		if string(got) != string(want) {
+			t.Errorf("Redact() = %q, which parses to %s, want %s", redacted, got, want)
+		}
+	}
+}
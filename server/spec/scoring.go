@@ -0,0 +1,98 @@
+package spec
+
+import (
+	"slices"
+	"strings"
+)
+
+// scoredTypes are the question types that can have an answer key.
+var scoredTypes = []QuestionType{
+	TypeInput, TypeTextarea, TypeRadio, TypeCheckbox, TypeSelect, TypeDate,
+}
+
+// QuestionScore is the outcome of scoring the answer to a single question.
+type QuestionScore struct {
+	Question string `json:"question"`
+	Correct  bool   `json:"correct"`
+	Points   int64  `json:"points"`
+	Maximum  int64  `json:"maximum"`
+}
+
+type Score struct {
+	Points    int64           `json:"points"`
+	Maximum   int64           `json:"maximum"`
+	Questions []QuestionScore `json:"questions"`
+}
+
+// Scored reports whether the question has an answer key.
+func (q *Question) Scored() bool {
+	return len(q.Correct) > 0
+}
+
+// IsCorrect reports whether an answer matches the answer key of the question.
+// Text answers may match any of the correct values, ignoring case and
+// surrounding whitespace, while checkbox answers must pick exactly the correct
+// options.
+func (q *Question) IsCorrect(raw []byte) bool {
+	if !q.Scored() || !q.Answered(raw) {
+		return false
+	}
+
+	value, err := decodeAnswer(raw)
+	if err != nil {
+		return false
+	}
+
+	switch q.Type {
+	case TypeInput, TypeTextarea:
+		text, _ := value.(string)
+		for _, correct := range q.Correct {
+			if strings.EqualFold(strings.TrimSpace(text), strings.TrimSpace(correct)) {
+				return true
+			}
+		}
+	case TypeRadio, TypeSelect, TypeDate:
+		answer, ok := optionValue(value)
+		return ok && slices.Contains(q.Correct, answer)
+	case TypeCheckbox:
+		values, _ := value.([]interface{})
+		if len(values) != len(q.Correct) {
+			return false
+		}
+
+		for _, v := range values {
+			answer, ok := optionValue(v)
+			if !ok || !slices.Contains(q.Correct, answer) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// Score marks the answers against the answer key of the form. Only questions
+// that are visible for the given answers count towards the maximum score.
+func (f *Form) Score(answers map[string][]byte) Score {
+	visible := f.Visible(answers)
+
+	score := Score{Questions: []QuestionScore{}}
+	for _, question := range f.Questions() {
+		if !question.Scored() || !visible[question.ID] {
+			continue
+		}
+
+		result := QuestionScore{Question: question.ID, Maximum: question.Points}
+		if question.IsCorrect(answers[question.ID]) {
+			result.Correct = true
+			result.Points = question.Points
+		}
+
+		score.Points += result.Points
+		score.Maximum += result.Maximum
+		score.Questions = append(score.Questions, result)
+	}
+
+	return score
+}
@@ -0,0 +1,131 @@
+package spec
+
+import "testing"
+
+const quiz = `form {
+	question id="capital" type="radio" {
+		title "What is the capital of France?"
+		option value="paris"
+		option value="lyon"
+		correct "paris"
+		points 2
+	}
+	question id="primes" type="checkbox" {
+		title "Which of these are prime?"
+		option value=2
+		option value=3
+		option value=4
+		correct 2 3
+	}
+	question id="author" type="input" {
+		title "Who wrote Hamlet?"
+		correct "Shakespeare" "William Shakespeare"
+	}
+	question id="bonus" type="date" {
+		title "When did the war end?"
+		visible-if {
+			answered question="author"
+		}
+		correct "1945-09-02"
+		points 3
+	}
+	question id="comments" type="textarea" {
+		title "Any comments?"
+	}
+}`
+
+func TestScore(t *testing.T) {
+	form := mustParse(t, quiz)
+
+	if q := form.Question("author"); q.Points != 1 {
+		t.Errorf("author.Points = %d, want the default of 1", q.Points)
+	}
+
+	tests := []struct {
+		name    string
+		answers map[string]string
+		points  int64
+		maximum int64
+	}{
+		{"nothing answered", map[string]string{}, 0, 4},
+		{
+			"all correct",
+			map[string]string{
+				"capital": `"paris"`, "primes": `[3, "2"]`, "author": `"  shakespeare "`, "bonus": `"1945-09-02"`,
+			},
+			7, 7,
+		},
+		{
+			"all wrong",
+			map[string]string{
+				"capital": `"lyon"`, "primes": `[2]`, "author": `"Marlowe"`, "bonus": `"1945-05-08"`,
+			},
+			0, 7,
+		},
+		{"too many options", map[string]string{"primes": `[2, 3, 4]`}, 0, 4},
+		{"alternative answer", map[string]string{"author": `"william shakespeare"`}, 1, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := form.Score(answers(tt.answers))
+			if score.Points != tt.points || score.Maximum != tt.maximum {
+				t.Errorf("Score() = %d/%d, want %d/%d", score.Points, score.Maximum, tt.points, tt.maximum)
+			}
+
+			for _, result := range score.Questions {
+				if result.Question == "comments" {
+					t.Errorf("Score() includes a question without an answer key")
+				}
+			}
+		})
+	}
+}
+
+func TestAnswerKeyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   string
+	}{
+		{
+			"not an option",
+			"form {\n\tquestion id=\"a\" type=\"radio\" {\n\t\ttitle \"a\"\n\t\toption value=1\n\t\tcorrect 2\n\t}\n}",
+			"5:11: 2 is not an option of 'a'",
+		},
+		{
+			"duplicate",
+			"form {\n\tquestion id=\"a\" type=\"input\" {\n\t\ttitle \"a\"\n\t\tcorrect \"x\" \"x\"\n\t}\n}",
+			"4:15: \"x\" is listed more than once",
+		},
+		{
+			"invalid date",
+			"form {\n\tquestion id=\"a\" type=\"date\" {\n\t\ttitle \"a\"\n\t\tcorrect \"soon\"\n\t}\n}",
+			"4:11: correct dates must be in the YYYY-MM-DD format",
+		},
+		{
+			"negative points",
+			"form {\n\tquestion id=\"a\" type=\"input\" {\n\t\ttitle \"a\"\n\t\tcorrect \"x\"\n\t\tpoints -1\n\t}\n}",
+			"5:3: points must not be negative",
+		},
+		{
+			"not scored",
+			"form {\n\tquestion id=\"a\" type=\"likert\" {\n\t\ttitle \"a\"\n\t\tcorrect 1\n\t}\n}",
+			"4:3: 'correct' is not allowed on likert questions",
+		},
+		{
+			"empty",
+			"form {\n\tquestion id=\"a\" type=\"input\" {\n\t\ttitle \"a\"\n\t\tcorrect\n\t}\n}",
+			"4:3: correct expects at least one value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.source)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Parse() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// ParticipantToken identifies a user within a single anonymous form, without
+// revealing who they are. It only serves to enforce the individual limit of
+// the form, and cannot be linked back to the user without the secret.
+func ParticipantToken(formID, userID string) string {
+	mac := hmac.New(sha256.New, []byte(Config.AnonymitySecret))
+	mac.Write([]byte(formID + ":" + userID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewResponseKey generates the key a respondent uses to access their response
+// to an anonymous form. Only its hash is stored, so it is shown only once.
+func NewResponseKey() (string, error) {
+	return randomToken()
+}
+
+func randomToken() (string, error) {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
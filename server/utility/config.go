@@ -2,6 +2,8 @@ package utils
 
 import (
 	"os"
+	"slices"
+	"strings"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -19,7 +21,13 @@ type config struct {
 	CasBaseUrl    string
 	CasServiceUrl string
 
+	// cas handles of the site administrators
+	Admins []string
+
 	SessionSecret string
+	// has no default, since anyone who knows it can link responses to
+	// anonymous forms back to their respondents
+	AnonymitySecret string
 }
 
 func defaultConfig() config {
@@ -36,6 +44,8 @@ func defaultConfig() config {
 		CasBaseUrl:    "https://login.iiit.ac.in/cas",
 		CasServiceUrl: "http://localhost:8647/api/auth/login/callback",
 
+		Admins: []string{},
+
 		SessionSecret: "quis-custodiet-ipsos-custodes",
 	}
 }
@@ -78,10 +88,28 @@ func LoadConfig() {
 		c.CasServiceUrl = casServiceUrl
 	}
 
+	admins, ok := os.LookupEnv("FORMS_ADMINS")
+	if ok {
+		c.Admins = []string{}
+		for _, handle := range strings.Split(admins, ",") {
+			if handle = strings.TrimSpace(handle); handle != "" {
+				c.Admins = append(c.Admins, handle)
+			}
+		}
+	}
+
 	sessionSecret, ok := os.LookupEnv("FORMS_SESSION_SECRET")
 	if ok {
 		c.SessionSecret = sessionSecret
 	}
+	anonymitySecret, ok := os.LookupEnv("FORMS_ANONYMITY_SECRET")
+	if ok {
+		c.AnonymitySecret = anonymitySecret
+	}
 
 	Config = c
 }
+
+func IsAdmin(handle string) bool {
+	return slices.Contains(Config.Admins, handle)
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"net/http"
+	"time"
+)
+
+const GuestCookieName = "guest"
+
+const DefaultGuestTtl = 365 * 24 * time.Hour
+
+// NewGuestToken generates the token that identifies a guest, who is not logged
+// in, in place of a user id. It is the only way back to their responses.
+func NewGuestToken() (string, error) {
+	return randomToken()
+}
+
+func GuestCookie(token string) *http.Cookie {
+	cookie := new(http.Cookie)
+	cookie.Name = GuestCookieName
+	cookie.Expires = time.Now().Add(DefaultGuestTtl)
+	cookie.HttpOnly = true
+	cookie.Path = "/"
+	cookie.SameSite = http.SameSiteLaxMode
+	cookie.Secure = Config.Domain[4] == 's' // only https
+	cookie.Value = token
+	return cookie
+}
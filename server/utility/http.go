@@ -20,12 +20,21 @@ const (
 	ErrorRateLimited  HttpErrorCode = "rate-limited"
 	ErrorInternal     HttpErrorCode = "internal-server-error"
 
-	ErrorFormClosed HttpErrorCode = "form-closed"
+	ErrorFormClosed       HttpErrorCode = "form-closed"
+	ErrorInvalidStructure HttpErrorCode = "invalid-structure"
+	ErrorInvalidAnswer    HttpErrorCode = "invalid-answer"
+	ErrorReservedSlug     HttpErrorCode = "reserved-slug"
+
+	ErrorIncompleteResponse HttpErrorCode = "incomplete-response"
+	ErrorResponseLocked     HttpErrorCode = "response-locked"
+	ErrorStaleRevision      HttpErrorCode = "stale-revision"
+	ErrorGroupCycle         HttpErrorCode = "group-cycle"
 )
 
 type HttpError struct {
 	Code    HttpErrorCode `json:"code"`
 	Message string        `json:"message"`
+	Errors  interface{}   `json:"errors,omitempty"`
 }
 
 type HttpResponse struct {
@@ -41,6 +50,16 @@ func FromError(code HttpErrorCode, err error) HttpResponse {
 	}
 }
 
+func FromErrors(code HttpErrorCode, err error, errs interface{}) HttpResponse {
+	return HttpResponse{
+		Error: &HttpError{
+			Code:    code,
+			Message: err.Error(),
+			Errors:  errs,
+		},
+	}
+}
+
 func ErrorHandler(err error, c echo.Context) {
 	if c.Response().Committed {
 		return
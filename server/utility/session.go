@@ -18,17 +18,21 @@ const SessionCookieName = "session"
 
 var secret = []byte(Config.SessionSecret)
 
+// Session is what the cookie holds. ID is the user, and Session is the stored
+// session, which has to still exist for the cookie to be valid.
 type Session struct {
 	ID      string    `json:"id"`
+	Session string    `json:"session"`
 	Expires time.Time `json:"expires"`
 }
 
 const DefaultSessionTtl = 7 * 24 * time.Hour
 
-func CreateSession(id string, ttl time.Duration) Session {
+func CreateSession(id string, session string, expires time.Time) Session {
 	return Session{
 		ID:      id,
-		Expires: time.Now().Add(ttl),
+		Session: session,
+		Expires: expires,
 	}
 }
 
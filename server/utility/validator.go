@@ -8,6 +8,12 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// FieldError describes a problem with a single field of a payload.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 func LoadValidator() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
 }